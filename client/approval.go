@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/invoice"
+	"github.com/sulusolutions/gol402/macaroons"
+)
+
+// Decision is the outcome of a payment approval.
+type Decision int
+
+const (
+	// Approve lets the payment go ahead.
+	Approve Decision = iota
+
+	// Reject refuses the payment; the request fails with ErrPaymentRejected.
+	Reject
+
+	// Defer skips the payment for now; the request fails with ErrPaymentDeferred
+	// so the caller can retry it later.
+	Defer
+)
+
+var (
+	// ErrPaymentRejected is returned when the approval func rejects a payment.
+	ErrPaymentRejected = errors.New("payment rejected")
+
+	// ErrPaymentDeferred is returned when the approval func defers a payment.
+	ErrPaymentDeferred = errors.New("payment deferred")
+)
+
+// PaymentRequest describes a payment the client is about to make.
+type PaymentRequest struct {
+	// URL is the resource the payment grants access to.
+	URL *url.URL
+
+	// Amount is the amount requested by the invoice, or zero if it does not specify one.
+	Amount lnwire.MilliSatoshi
+
+	// Description is the invoice description.
+	Description string
+
+	// Services lists the services the macaroon grants access to, as found in its
+	// "services" caveat, e.g. "randomnumber:0". It is empty for opaque macaroons.
+	Services []string
+
+	// Invoice is the decoded invoice.
+	Invoice *invoice.Invoice
+
+	// Challenge is the challenge the payment answers.
+	Challenge *Challenge
+}
+
+// ApprovalFunc is called before an invoice is paid and decides whether the payment may be made.
+// It may block, e.g. to prompt a user, and should return when ctx is done.
+// Returning an error fails the request without paying.
+type ApprovalFunc func(ctx context.Context, req *PaymentRequest) (Decision, error)
+
+// macaroonServices returns the services listed in the caveats of a base64 encoded macaroon.
+func macaroonServices(encoded string) []string {
+	mac, err := macaroons.Decode(encoded)
+	if err != nil {
+		return nil
+	}
+
+	var services []string
+	for _, c := range mac.Caveats() {
+		caveat, err := macaroons.ParseCaveat(string(c.Id))
+		if err != nil || caveat.Condition != macaroons.ServicesCondition {
+			continue
+		}
+		parsed, err := macaroons.ParseServices(caveat.Value)
+		if err != nil {
+			continue
+		}
+		for _, service := range parsed {
+			services = append(services, service.String())
+		}
+	}
+	return services
+}
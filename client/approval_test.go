@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/macaroons"
+	"github.com/sulusolutions/gol402/tokenstore"
+	"github.com/sulusolutions/gol402/wallet"
+	"gopkg.in/macaroon.v2"
+)
+
+func TestTransportApproval(t *testing.T) {
+	preimage, err := lntypes.MakePreimageFromStr(wallet.MockPreimage)
+	require.NoError(t, err)
+
+	// Build an L402 macaroon restricted to a service.
+	mac, err := macaroons.Decode(newTestMacaroon(t, preimage.Hash()))
+	require.NoError(t, err)
+	require.NoError(t, mac.AddFirstPartyCaveat([]byte("services=randomnumber:0, premium:1")))
+	encodedMac, err := macaroons.Encode(mac)
+	require.NoError(t, err)
+
+	invoice := newTestInvoice(t, 2000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `L402 macaroon="`+encodedMac+`", invoice="`+invoice+`"`)
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	errApproval := errors.New("approval error")
+	tests := []struct {
+		name      string
+		decision  Decision
+		err       error
+		wantError error
+	}{
+		{name: "Approved", decision: Approve},
+		{name: "Rejected", decision: Reject, wantError: ErrPaymentRejected},
+		{name: "Deferred", decision: Defer, wantError: ErrPaymentDeferred},
+		{name: "Unknown decision", decision: Decision(42), wantError: ErrPaymentRejected},
+		{name: "Approval error", err: errApproval, wantError: errApproval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &blockingWallet{unblock: make(chan struct{})}
+			close(w.unblock)
+
+			var got *PaymentRequest
+			c := New(w, tokenstore.NewNoopStore(), WithApproval(func(ctx context.Context, req *PaymentRequest) (Decision, error) {
+				got = req
+				return tt.decision, tt.err
+			}))
+
+			req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL+"/random", nil)
+			require.NoError(t, err)
+
+			resp, err := c.Do(req)
+			if tt.wantError != nil {
+				require.ErrorIs(t, err, tt.wantError)
+				require.Equal(t, 0, w.payments)
+			} else {
+				require.NoError(t, err)
+				resp.Body.Close()
+				require.Equal(t, 1, w.payments)
+			}
+
+			require.NotNil(t, got)
+			require.Equal(t, "/random", got.URL.Path)
+			require.Equal(t, lnwire.MilliSatoshi(2000), got.Amount)
+			require.Equal(t, "test", got.Description)
+			require.Equal(t, []string{"randomnumber:0", "premium:1"}, got.Services)
+			require.Equal(t, "L402", got.Challenge.HeaderKey)
+		})
+	}
+}
+
+// TestMacaroonServicesOpaque verifies that opaque macaroons have no services.
+func TestMacaroonServicesOpaque(t *testing.T) {
+	require.Empty(t, macaroonServices("testMacaroon"))
+
+	mac, err := macaroon.New([]byte("root key"), []byte("id"), "test", macaroon.LatestVersion)
+	require.NoError(t, err)
+	raw, err := mac.MarshalBinary()
+	require.NoError(t, err)
+	require.Empty(t, macaroonServices(base64.StdEncoding.EncodeToString(raw)))
+}
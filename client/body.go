@@ -0,0 +1,61 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxReplayBodySize is the default number of request body bytes the transport buffers
+// so that a request can be replayed after paying an invoice.
+const DefaultMaxReplayBodySize int64 = 1 << 20
+
+// ErrBodyNotReplayable is returned when a payment challenge is received for a request whose body
+// cannot be sent again, either because it has no GetBody func and exceeds the buffering limit,
+// or because buffering is disabled. No payment is made in this case.
+var ErrBodyNotReplayable = errors.New("request body cannot be replayed after payment")
+
+// prepareBody makes sure the body of req can be sent a second time after paying an invoice.
+// Requests without a body or with a GetBody func are already replayable. Other bodies are
+// buffered in memory up to limit bytes; larger bodies are streamed as-is and reported as not
+// replayable. A negative limit disables buffering.
+func prepareBody(req *http.Request, limit int64) (bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return true, nil
+	}
+	if limit < 0 {
+		return false, nil
+	}
+
+	original := req.Body
+	buf, err := io.ReadAll(io.LimitReader(original, limit+1))
+	if err != nil {
+		original.Close()
+		return false, err
+	}
+
+	if int64(len(buf)) > limit {
+		// Too large to buffer: send what was read followed by the rest of the original body.
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), original), original}
+		return false, nil
+	}
+
+	original.Close()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return true, nil
+}
+
+// replayBody returns a fresh copy of the body of a request prepared by prepareBody.
+func replayBody(req *http.Request) (io.ReadCloser, error) {
+	if req.GetBody == nil {
+		return req.Body, nil
+	}
+	return req.GetBody()
+}
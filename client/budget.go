@@ -0,0 +1,180 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+const (
+	// day is the length of the rolling window used for daily limits.
+	day = 24 * time.Hour
+
+	// month is the length of the rolling window used for monthly limits.
+	month = 30 * day
+)
+
+// ErrBudgetExceeded is returned when paying an invoice would exceed one of the configured spending limits.
+// The invoice is not paid in this case.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetLimits holds the spending limits enforced by a Budget. A zero value means no limit.
+// Daily and monthly limits apply over rolling 24 hour and 30 day windows, and count the routing
+// fees of payments once they are known.
+type BudgetLimits struct {
+	// MaxPerRequest is the maximum amount paid for a single invoice.
+	MaxPerRequest lnwire.MilliSatoshi
+
+	// HostDaily and HostMonthly limit the amount paid to each host.
+	HostDaily   lnwire.MilliSatoshi
+	HostMonthly lnwire.MilliSatoshi
+
+	// GlobalDaily and GlobalMonthly limit the amount paid across all hosts.
+	GlobalDaily   lnwire.MilliSatoshi
+	GlobalMonthly lnwire.MilliSatoshi
+}
+
+// spend records a single payment counted against the budget.
+type spend struct {
+	id     uint64
+	host   string
+	amount lnwire.MilliSatoshi
+	at     time.Time
+}
+
+// Budget tracks cumulative spending and refuses payments that would exceed its limits.
+// It is safe for concurrent use; amounts are reserved before paying so concurrent
+// requests cannot overspend together.
+type Budget struct {
+	limits BudgetLimits
+
+	mu     sync.Mutex
+	spends []spend
+	lastID uint64
+	now    func() time.Time
+}
+
+// reservation is an amount reserved for a payment in progress. The nil reservation does nothing,
+// for payments made without a budget.
+type reservation struct {
+	budget *Budget
+	id     uint64
+}
+
+// NewBudget creates a new Budget enforcing the given limits.
+func NewBudget(limits BudgetLimits) *Budget {
+	return &Budget{
+		limits: limits,
+		now:    time.Now,
+	}
+}
+
+// Spent returns the amount paid to host within the given window. An empty host returns
+// the amount paid across all hosts.
+func (b *Budget) Spent(host string, window time.Duration) lnwire.MilliSatoshi {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.spentLocked(host, b.now().Add(-window))
+}
+
+// reserve checks amount against the limits and, if allowed, records it as spent for host.
+// The reservation must be released if the payment does not go through, and settled with the
+// amount actually paid if it does.
+func (b *Budget) reserve(host string, amount lnwire.MilliSatoshi) (*reservation, error) {
+	if b.limits.MaxPerRequest != 0 && amount > b.limits.MaxPerRequest {
+		return nil, fmt.Errorf("%w: invoice amount %v is above the per-request limit of %v",
+			ErrBudgetExceeded, amount, b.limits.MaxPerRequest)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.prune(now)
+
+	checks := []struct {
+		name   string
+		host   string
+		window time.Duration
+		limit  lnwire.MilliSatoshi
+	}{
+		{"daily limit for " + host, host, day, b.limits.HostDaily},
+		{"monthly limit for " + host, host, month, b.limits.HostMonthly},
+		{"global daily limit", "", day, b.limits.GlobalDaily},
+		{"global monthly limit", "", month, b.limits.GlobalMonthly},
+	}
+	for _, c := range checks {
+		if c.limit == 0 {
+			continue
+		}
+		if spent := b.spentLocked(c.host, now.Add(-c.window)); spent+amount > c.limit {
+			return nil, fmt.Errorf("%w: paying %v would exceed the %s of %v (already spent %v)",
+				ErrBudgetExceeded, amount, c.name, c.limit, spent)
+		}
+	}
+
+	b.lastID++
+	b.spends = append(b.spends, spend{id: b.lastID, host: host, amount: amount, at: now})
+
+	return &reservation{budget: b, id: b.lastID}, nil
+}
+
+// release refunds the reserved amount.
+func (r *reservation) release() {
+	if r == nil {
+		return
+	}
+	b := r.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, s := range b.spends {
+		if s.id == r.id {
+			b.spends = append(b.spends[:i], b.spends[i+1:]...)
+			return
+		}
+	}
+}
+
+// settle replaces the reserved amount with the amount actually paid, including fees.
+// The payment has already been made, so the limits are not checked again.
+func (r *reservation) settle(amount lnwire.MilliSatoshi) {
+	if r == nil {
+		return
+	}
+	b := r.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, s := range b.spends {
+		if s.id == r.id {
+			b.spends[i].amount = amount
+			return
+		}
+	}
+}
+
+// spentLocked sums the spends for host (or all hosts if empty) made after since. b.mu must be held.
+func (b *Budget) spentLocked(host string, since time.Time) lnwire.MilliSatoshi {
+	var total lnwire.MilliSatoshi
+	for _, s := range b.spends {
+		if s.at.After(since) && (host == "" || s.host == host) {
+			total += s.amount
+		}
+	}
+	return total
+}
+
+// prune drops spends that fall outside of the longest window. b.mu must be held.
+func (b *Budget) prune(now time.Time) {
+	cutoff := now.Add(-month)
+	i := 0
+	for i < len(b.spends) && !b.spends[i].at.After(cutoff) {
+		i++
+	}
+	b.spends = b.spends[i:]
+}
@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/tokenstore"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+func TestBudgetReserve(t *testing.T) {
+	tests := []struct {
+		name      string
+		limits    BudgetLimits
+		spends    []lnwire.MilliSatoshi
+		host      string
+		amount    lnwire.MilliSatoshi
+		wantError bool
+	}{
+		{
+			name:   "No limits",
+			amount: 1_000_000,
+		},
+		{
+			name:      "Above per-request limit",
+			limits:    BudgetLimits{MaxPerRequest: 1000},
+			amount:    1001,
+			wantError: true,
+		},
+		{
+			name:   "Within host daily limit",
+			limits: BudgetLimits{HostDaily: 1000},
+			spends: []lnwire.MilliSatoshi{500},
+			host:   "host.com",
+			amount: 500,
+		},
+		{
+			name:      "Above host daily limit",
+			limits:    BudgetLimits{HostDaily: 1000},
+			spends:    []lnwire.MilliSatoshi{500},
+			host:      "host.com",
+			amount:    501,
+			wantError: true,
+		},
+		{
+			name:   "Host limit ignores other hosts",
+			limits: BudgetLimits{HostMonthly: 1000},
+			spends: []lnwire.MilliSatoshi{900},
+			host:   "other.com",
+			amount: 900,
+		},
+		{
+			name:      "Above global monthly limit",
+			limits:    BudgetLimits{GlobalMonthly: 1000},
+			spends:    []lnwire.MilliSatoshi{900},
+			host:      "other.com",
+			amount:    200,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBudget(tt.limits)
+			for _, amount := range tt.spends {
+				_, err := b.reserve("host.com", amount)
+				require.NoError(t, err)
+			}
+
+			_, err := b.reserve(tt.host, tt.amount)
+			if tt.wantError {
+				require.ErrorIs(t, err, ErrBudgetExceeded)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestBudgetRollingWindow verifies that old payments stop counting once they leave the window.
+func TestBudgetRollingWindow(t *testing.T) {
+	now := time.Now()
+	b := NewBudget(BudgetLimits{GlobalDaily: 1000})
+	b.now = func() time.Time { return now }
+
+	_, err := b.reserve("host.com", 1000)
+	require.NoError(t, err)
+
+	_, err = b.reserve("host.com", 1)
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+
+	now = now.Add(day + time.Second)
+	_, err = b.reserve("host.com", 1000)
+	require.NoError(t, err)
+	require.Equal(t, lnwire.MilliSatoshi(2000), b.Spent("host.com", month))
+}
+
+// TestBudgetRelease verifies that releasing a reservation refunds it.
+func TestBudgetRelease(t *testing.T) {
+	b := NewBudget(BudgetLimits{GlobalDaily: 1000})
+
+	reserved, err := b.reserve("host.com", 1000)
+	require.NoError(t, err)
+	reserved.release()
+
+	require.Equal(t, lnwire.MilliSatoshi(0), b.Spent("", day))
+	_, err = b.reserve("host.com", 1000)
+	require.NoError(t, err)
+}
+
+// TestBudgetSettle verifies that settling a reservation counts the amount actually paid.
+func TestBudgetSettle(t *testing.T) {
+	b := NewBudget(BudgetLimits{GlobalDaily: 1000})
+
+	first, err := b.reserve("host.com", 500)
+	require.NoError(t, err)
+	second, err := b.reserve("host.com", 500)
+	require.NoError(t, err)
+
+	first.settle(510)
+	require.Equal(t, lnwire.MilliSatoshi(1010), b.Spent("", day))
+
+	// Releasing a reservation of the same amount leaves the settled one counted.
+	second.release()
+	require.Equal(t, lnwire.MilliSatoshi(510), b.Spent("", day))
+	_, err = b.reserve("host.com", 491)
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+}
+
+// TestBudgetConcurrentReserve verifies that concurrent reservations never overspend.
+func TestBudgetConcurrentReserve(t *testing.T) {
+	b := NewBudget(BudgetLimits{GlobalDaily: 500})
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.reserve("host.com", 100); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 5, succeeded)
+	require.Equal(t, lnwire.MilliSatoshi(500), b.Spent("", day))
+}
+
+// TestTransportBudget verifies that the transport refuses to pay invoices above the budget.
+func TestTransportBudget(t *testing.T) {
+	invoice := newTestInvoice(t, 5000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		limits    BudgetLimits
+		wantError bool
+	}{
+		{
+			name:   "Within budget",
+			limits: BudgetLimits{MaxPerRequest: 5000},
+		},
+		{
+			name:      "Above budget",
+			limits:    BudgetLimits{MaxPerRequest: 4999},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewBudget(tt.limits)
+			c := New(wallet.NewMockWallet(nil), tokenstore.NewNoopStore(), WithBudget(budget))
+
+			req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+			require.NoError(t, err)
+
+			resp, err := c.Do(req)
+			if tt.wantError {
+				require.ErrorIs(t, err, ErrBudgetExceeded)
+				require.Equal(t, lnwire.MilliSatoshi(0), budget.Spent("", day))
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, lnwire.MilliSatoshi(5000), budget.Spent("", day))
+		})
+	}
+}
+
+// feeWallet reports a routing fee for every payment.
+type feeWallet struct {
+	fee lnwire.MilliSatoshi
+}
+
+func (fw *feeWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	result, err := wallet.NewMockWallet(nil).PayInvoice(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+	result.Fee = fw.fee
+	return result, nil
+}
+
+// TestTransportBudgetFees verifies that the routing fees of payments count against the budget.
+func TestTransportBudgetFees(t *testing.T) {
+	invoice := newTestInvoice(t, 5000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	budget := NewBudget(BudgetLimits{GlobalDaily: 10000})
+	c := New(&feeWallet{fee: 25}, tokenstore.NewNoopStore(), WithBudget(budget))
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	require.NoError(t, err)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, lnwire.MilliSatoshi(5025), budget.Spent("", day))
+
+	// With the fee of the first payment counted, a second invoice no longer fits.
+	req, err = http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	require.NoError(t, err)
+	_, err = c.Do(req)
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+}
+
+// TestTransportBudgetRefunds verifies that only payments that definitely failed are refunded,
+// while payments that may still settle stay counted.
+func TestTransportBudgetRefunds(t *testing.T) {
+	invoice := newTestInvoice(t, 5000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+		w.WriteHeader(http.StatusPaymentRequired)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		wallet    wallet.Wallet
+		wantError error
+		wantSpent lnwire.MilliSatoshi
+	}{
+		{
+			name:      "Failed payment is refunded",
+			wallet:    wallet.NewMockWallet(errors.New("no route")),
+			wantError: ErrPaymentFailed,
+		},
+		{
+			name:      "Payment abandoned on timeout stays counted",
+			wallet:    &cancelingWallet{},
+			wantError: wallet.ErrPaymentPending,
+			wantSpent: 5000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewBudget(BudgetLimits{})
+			c := New(tt.wallet, tokenstore.NewNoopStore(), WithBudget(budget))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+			require.NoError(t, err)
+
+			_, err = c.Do(req)
+			require.ErrorIs(t, err, tt.wantError)
+			require.Equal(t, tt.wantSpent, budget.Spent("", day))
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultPreferredSchemes lists the authentication schemes the client pays for, most preferred first.
+var DefaultPreferredSchemes = []string{"L402", "LSAT"}
+
+// Challenge holds a parsed authentication challenge from the WWW-Authenticate header.
+type Challenge struct {
+	// HeaderKey is the authentication scheme of the challenge, e.g. L402 or LSAT.
+	HeaderKey string
+
+	// Invoice, Macaroon and Version hold the invoice, macaroon and version parameters, if present.
+	Invoice  string
+	Macaroon string
+	Version  string
+
+	// Params holds all auth-params of the challenge, keyed by lowercased name.
+	Params map[string]string
+
+	// Token68 holds the token68 credentials of challenges that use them instead of auth-params.
+	Token68 string
+}
+
+// ParseChallenges parses all challenges offered in the given WWW-Authenticate header values,
+// as defined by RFC 7235. Challenges of any scheme are returned in the order they appear.
+func ParseChallenges(headers []string) ([]Challenge, error) {
+	var challenges []Challenge
+	for _, header := range headers {
+		p := &headerParser{s: header}
+		for {
+			p.skipListSeparators()
+			if p.eof() {
+				break
+			}
+
+			challenge, err := p.challenge()
+			if err != nil {
+				return nil, fmt.Errorf("malformed WWW-Authenticate header: %w", err)
+			}
+			challenges = append(challenges, *challenge)
+		}
+	}
+	return challenges, nil
+}
+
+// selectChallenge returns the first challenge using the most preferred scheme that carries
+// both a macaroon and an invoice. Challenges missing either are skipped; if no challenge is
+// complete, the error reports the first one that was incomplete.
+func selectChallenge(challenges []Challenge, preferred []string) (*Challenge, error) {
+	var incomplete error
+	for _, scheme := range preferred {
+		for i := range challenges {
+			if !strings.EqualFold(challenges[i].HeaderKey, scheme) {
+				continue
+			}
+			switch {
+			case challenges[i].Invoice == "":
+				if incomplete == nil {
+					incomplete = fmt.Errorf("invoice not found in %s challenge", scheme)
+				}
+			case challenges[i].Macaroon == "":
+				if incomplete == nil {
+					incomplete = fmt.Errorf("macaroon not found in %s challenge", scheme)
+				}
+			default:
+				return &challenges[i], nil
+			}
+		}
+	}
+	if incomplete != nil {
+		return nil, incomplete
+	}
+	return nil, fmt.Errorf("no %s challenge found in WWW-Authenticate header", strings.Join(preferred, " or "))
+}
+
+// parseHeader parses the WWW-Authenticate header values and selects the preferred payable challenge.
+func parseHeader(headers []string, preferred []string) (*Challenge, error) {
+	challenges, err := ParseChallenges(headers)
+	if err != nil {
+		return nil, err
+	}
+	return selectChallenge(challenges, preferred)
+}
+
+// constructL402Token constructs the L402 token from the given Challenge and preimage.
+func constructL402Token(challenge Challenge, preimage string) string {
+	// Construct and return the token using fmt.Sprintf for formatting
+	return fmt.Sprintf("%s %s:%s", challenge.HeaderKey, challenge.Macaroon, preimage)
+}
+
+// headerParser parses a single WWW-Authenticate header value.
+type headerParser struct {
+	s   string
+	pos int
+}
+
+// challenge parses a challenge: auth-scheme [ 1*SP ( token68 / #auth-param ) ].
+func (p *headerParser) challenge() (*Challenge, error) {
+	scheme := p.token()
+	if scheme == "" {
+		return nil, fmt.Errorf("expected auth-scheme at offset %d", p.pos)
+	}
+	c := &Challenge{HeaderKey: scheme, Params: make(map[string]string)}
+
+	if !p.skipSpace() || p.eof() || p.peek() == ',' {
+		return c, nil
+	}
+
+	if token68, ok := p.token68(); ok {
+		c.Token68 = token68
+		return c, nil
+	}
+
+	for {
+		start := p.pos
+		name := p.token()
+		if name == "" {
+			return nil, fmt.Errorf("expected auth-param at offset %d", p.pos)
+		}
+
+		p.skipSpace()
+		if p.eof() || p.peek() != '=' {
+			// Not an auth-param: this is the scheme of the next challenge.
+			p.pos = start
+			break
+		}
+		p.pos++
+		p.skipSpace()
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		name = strings.ToLower(name)
+		if _, exists := c.Params[name]; exists {
+			return nil, fmt.Errorf("duplicate auth-param %q", name)
+		}
+		c.Params[name] = value
+
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("unexpected character %q at offset %d", p.peek(), p.pos)
+		}
+		p.skipListSeparators()
+		if p.eof() {
+			break
+		}
+	}
+
+	c.Invoice = c.Params["invoice"]
+	c.Macaroon = c.Params["macaroon"]
+	c.Version = c.Params["version"]
+	return c, nil
+}
+
+// value parses an auth-param value: token / quoted-string.
+func (p *headerParser) value() (string, error) {
+	if !p.eof() && p.peek() == '"' {
+		return p.quotedString()
+	}
+	if v := p.token(); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("expected auth-param value at offset %d", p.pos)
+}
+
+// quotedString parses a quoted-string, resolving quoted-pairs.
+func (p *headerParser) quotedString() (string, error) {
+	start := p.pos
+	p.pos++ // opening quote
+
+	var b strings.Builder
+	for !p.eof() {
+		c := p.s[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\\':
+			if p.pos+1 >= len(p.s) {
+				return "", fmt.Errorf("unterminated quoted-string at offset %d", start)
+			}
+			b.WriteByte(p.s[p.pos+1])
+			p.pos += 2
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated quoted-string at offset %d", start)
+}
+
+// token68 parses credentials of the form 1*( ALPHA / DIGIT / "-" / "." / "_" / "~" / "+" / "/" ) *"=".
+// It only succeeds if the token68 is followed by the end of the challenge, since otherwise
+// the input is the start of an auth-param.
+func (p *headerParser) token68() (string, bool) {
+	start := p.pos
+	end := start
+	for end < len(p.s) && isToken68Char(p.s[end]) {
+		end++
+	}
+	if end == start {
+		return "", false
+	}
+	for end < len(p.s) && p.s[end] == '=' {
+		end++
+	}
+
+	rest := strings.TrimLeft(p.s[end:], " \t")
+	if rest != "" && rest[0] != ',' {
+		return "", false
+	}
+
+	p.pos = end
+	return p.s[start:end], true
+}
+
+// token parses a token as defined by RFC 7230.
+func (p *headerParser) token() string {
+	start := p.pos
+	for !p.eof() && isTokenChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// skipSpace skips optional whitespace and reports whether any was found.
+func (p *headerParser) skipSpace() bool {
+	start := p.pos
+	for !p.eof() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// skipListSeparators skips commas and whitespace between list elements.
+func (p *headerParser) skipListSeparators() {
+	for !p.eof() && (p.s[p.pos] == ',' || p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *headerParser) peek() byte {
+	return p.s[p.pos]
+}
+
+func (p *headerParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+// isTokenChar reports whether c is a tchar as defined by RFC 7230.
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// isToken68Char reports whether c may appear in a token68 before its trailing padding.
+func isToken68Char(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("-._~+/", c) >= 0
+}
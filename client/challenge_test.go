@@ -0,0 +1,161 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChallenges(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    []Challenge
+	}{
+		{
+			name:    "Single L402 challenge",
+			headers: []string{`L402 macaroon="mac", invoice="lnbc1"`},
+			want: []Challenge{{
+				HeaderKey: "L402", Macaroon: "mac", Invoice: "lnbc1",
+				Params: map[string]string{"macaroon": "mac", "invoice": "lnbc1"},
+			}},
+		},
+		{
+			name:    "Token values, extra params and odd spacing",
+			headers: []string{`L402   Macaroon = mac ,invoice=lnbc1,version=0 ,  realm="api"`},
+			want: []Challenge{{
+				HeaderKey: "L402", Macaroon: "mac", Invoice: "lnbc1", Version: "0",
+				Params: map[string]string{"macaroon": "mac", "invoice": "lnbc1", "version": "0", "realm": "api"},
+			}},
+		},
+		{
+			name:    "Escaped quotes and commas inside quoted-string",
+			headers: []string{`L402 macaroon="a\"b,c", invoice="lnbc1"`},
+			want: []Challenge{{
+				HeaderKey: "L402", Macaroon: `a"b,c`, Invoice: "lnbc1",
+				Params: map[string]string{"macaroon": `a"b,c`, "invoice": "lnbc1"},
+			}},
+		},
+		{
+			name:    "Multiple challenges in one header",
+			headers: []string{`Basic realm="api", LSAT macaroon="m1", invoice="i1", L402 macaroon="m2", invoice="i2"`},
+			want: []Challenge{
+				{HeaderKey: "Basic", Params: map[string]string{"realm": "api"}},
+				{HeaderKey: "LSAT", Macaroon: "m1", Invoice: "i1", Params: map[string]string{"macaroon": "m1", "invoice": "i1"}},
+				{HeaderKey: "L402", Macaroon: "m2", Invoice: "i2", Params: map[string]string{"macaroon": "m2", "invoice": "i2"}},
+			},
+		},
+		{
+			name:    "Multiple headers with token68 and bare scheme",
+			headers: []string{`Negotiate YWJj==`, `Custom, L402 macaroon="m", invoice="i"`},
+			want: []Challenge{
+				{HeaderKey: "Negotiate", Token68: "YWJj==", Params: map[string]string{}},
+				{HeaderKey: "Custom", Params: map[string]string{}},
+				{HeaderKey: "L402", Macaroon: "m", Invoice: "i", Params: map[string]string{"macaroon": "m", "invoice": "i"}},
+			},
+		},
+		{
+			name:    "Empty header",
+			headers: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChallenges(tt.headers)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseChallengesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"Unterminated quote", `L402 macaroon="mac, invoice="lnbc1`},
+		{"Missing value", `L402 macaroon=, invoice="lnbc1"`},
+		{"Duplicate param", `L402 macaroon="a", macaroon="b"`},
+		{"Garbage after value", `L402 macaroon="a" invoice="b"`},
+		{"Missing scheme", `="a"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseChallenges([]string{tt.header})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSelectChallenge(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		preferred  []string
+		wantScheme string
+		wantMac    string
+		wantError  bool
+	}{
+		{
+			name:       "Prefers L402 over LSAT",
+			header:     `LSAT macaroon="m1", invoice="i1", L402 macaroon="m2", invoice="i2"`,
+			preferred:  DefaultPreferredSchemes,
+			wantScheme: "L402",
+		},
+		{
+			name:       "Falls back to LSAT",
+			header:     `Basic realm="api", LSAT macaroon="m1", invoice="i1"`,
+			preferred:  DefaultPreferredSchemes,
+			wantScheme: "LSAT",
+		},
+		{
+			name:       "Custom preference",
+			header:     `LSAT macaroon="m1", invoice="i1", L402 macaroon="m2", invoice="i2"`,
+			preferred:  []string{"LSAT"},
+			wantScheme: "LSAT",
+		},
+		{
+			name:      "Rejects look-alike scheme",
+			header:    `L402X macaroon="m", invoice="i"`,
+			preferred: DefaultPreferredSchemes,
+			wantError: true,
+		},
+		{
+			name:       "Skips incomplete challenge for another scheme",
+			header:     `L402 macaroon="m1", LSAT macaroon="m2", invoice="i2"`,
+			preferred:  DefaultPreferredSchemes,
+			wantScheme: "LSAT",
+			wantMac:    "m2",
+		},
+		{
+			name:       "Skips incomplete challenge of the same scheme",
+			header:     `L402 invoice="i1", L402 macaroon="m2", invoice="i2"`,
+			preferred:  DefaultPreferredSchemes,
+			wantScheme: "L402",
+			wantMac:    "m2",
+		},
+		{
+			name:      "Missing invoice",
+			header:    `L402 macaroon="m"`,
+			preferred: DefaultPreferredSchemes,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge, err := parseHeader([]string{tt.header}, tt.preferred)
+			if tt.wantError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantScheme, challenge.HeaderKey)
+			if tt.wantMac != "" {
+				require.Equal(t, tt.wantMac, challenge.Macaroon)
+			}
+		})
+	}
+}
@@ -1,129 +1,120 @@
 package client // import "github.com/sulusolutions/l402"
 
 import (
-	"context"
-	"fmt"
+	"log/slog"
 	"net/http"
-	"net/url"
-	"regexp"
+	"time"
 
 	"github.com/sulusolutions/gol402/tokenstore"
 	"github.com/sulusolutions/gol402/wallet"
 )
 
-// Challenge holds the parsed invoice and macaroon from the WWW-Authenticate header.
-type Challenge struct {
-	HeaderKey string
-	Invoice   string
-	Macaroon  string
-}
-
 // Client represents a client capable of handling L402 payments and making authenticated requests.
 type Client struct {
-	wallet wallet.Wallet
-	store  tokenstore.Store
+	transport  *Transport
+	httpClient *http.Client
 }
 
-// New creates a new L402 client with the provided wallet for handling payments
-// and token store for storing L402 tokens.
-func New(w wallet.Wallet, s tokenstore.Store) *Client {
-	return &Client{
-		wallet: w,
-		store:  s,
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through the given http.Client, keeping its timeout, cookie jar
+// and redirect policy. Its Transport, or http.DefaultTransport if nil, becomes the base of the L402 transport.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		httpClient := *hc
+		c.transport.Base = httpClient.Transport
+		httpClient.Transport = c.transport
+		c.httpClient = &httpClient
 	}
 }
 
-// Do makes an HTTP request and handles L402 payment challenges.
-// It automatically pays the invoice and retries the request with the L402 token if a 402 Payment Required response is received.
-func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	// Try to retrieve and use L402 token if available
-	l402Token, ok := c.store.Get(req.URL)
-	if ok {
-		req.Header.Set("Authorization", "L402 "+string(l402Token))
+// WithUserAgent sets the User-Agent header on requests that do not already have one.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.transport.UserAgent = userAgent
 	}
+}
 
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+// WithMaxRetries sets how many times a request is retried with a newly purchased token
+// after the server rejects the current one. Zero or a negative value disables these retries.
+func WithMaxRetries(n int) Option {
+	return func(c *Client) {
+		if n <= 0 {
+			// A zero MaxRepurchases means DefaultMaxRepurchases, so disable them explicitly.
+			n = -1
+		}
+		c.transport.MaxRepurchases = n
 	}
+}
 
-	if response.StatusCode == http.StatusPaymentRequired {
-		authHeader := response.Header.Get("WWW-Authenticate")
-		return c.handlePaymentChallenge(req.Context(), authHeader, req.URL.String(), req.Method)
+// WithMaxReplayBodySize sets how many bytes of a request body are buffered so the request can be
+// replayed after payment. A negative value disables buffering.
+func WithMaxReplayBodySize(n int64) Option {
+	return func(c *Client) {
+		c.transport.MaxReplayBodySize = n
 	}
-
-	return response, nil
 }
 
-// handlePaymentChallenge handles the 402 Payment Required response by extracting the invoice and macaroon,
-// paying the invoice, and constructing the L402 token for retrying the request.
-func (c *Client) handlePaymentChallenge(ctx context.Context, authHeader, rawUrl, method string) (*http.Response, error) {
-	challenge, err := parseHeader(authHeader)
-	if err != nil {
-		return nil, err
+// WithPreferredSchemes sets the challenge schemes the client pays for, most preferred first.
+func WithPreferredSchemes(schemes ...string) Option {
+	return func(c *Client) {
+		c.transport.PreferredSchemes = schemes
 	}
+}
 
-	// Pay the invoice using the wallet
-	paymentResult, err := c.wallet.PayInvoice(ctx, wallet.Invoice(challenge.Invoice))
-	if err != nil {
-		return nil, err
+// WithLogger sets the logger used to report payments and token lifecycle events.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.transport.Logger = logger
 	}
+}
 
-	// Construct L402 token using the challenge details and the preimage from the payment result
-	l402Token := constructL402Token(*challenge, paymentResult.Preimage)
-
-	// Prepare a new request for retrying with the L402 token
-	retryReq, err := http.NewRequestWithContext(ctx, method, rawUrl, nil)
-	if err != nil {
-		return nil, err
-	}
-	retryReq.Header.Set("Authorization", l402Token)
-	u, err := url.Parse(rawUrl)
-	if err != nil {
-		// For now we will just warn and continue, but this should be handled more gracefully.
-		fmt.Printf("error parsing url: %v\n", err)
+// WithApproval calls fn before each payment to approve, reject or defer it.
+func WithApproval(fn ApprovalFunc) Option {
+	return func(c *Client) {
+		c.transport.Approve = fn
 	}
-
-	c.store.Put(u, tokenstore.Token(l402Token))
-
-	// Retry the request with Authorization header
-	return http.DefaultClient.Do(retryReq)
 }
 
-var (
-	headerKeyRegex = regexp.MustCompile(`^(LSAT|L402)`)
-	invoiceRegex   = regexp.MustCompile(`invoice="([^"]+)"`)
-	macaroonRegex  = regexp.MustCompile(`macaroon="([^"]+)"`)
-)
+// WithBudget limits how much the client pays for invoices.
+func WithBudget(b *Budget) Option {
+	return func(c *Client) {
+		c.transport.Budget = b
+	}
+}
 
-// parseHeader uses regular expressions to extract the header key, invoice, and macaroon from the WWW-Authenticate header.
-func parseHeader(header string) (*Challenge, error) {
-	// Find matches using the pre-compiled regex
-	headerKeyMatch := headerKeyRegex.FindString(header)
-	invoiceMatch := invoiceRegex.FindStringSubmatch(header)
-	macaroonMatch := macaroonRegex.FindStringSubmatch(header)
+// WithTokenTTL sets how long purchased tokens are assumed to remain valid when their
+// macaroon does not carry an expiry. Expired tokens are replaced instead of being sent.
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.transport.TokenTTL = ttl
+	}
+}
 
-	// Check for each match and return specific errors
-	if headerKeyMatch == "" {
-		return nil, fmt.Errorf("header key (LSAT or L402) not found in WWW-Authenticate header")
+// WithContextStore stores tokens in a context-aware token store instead of the store passed to New.
+func WithContextStore(s tokenstore.ContextStore) Option {
+	return func(c *Client) {
+		c.transport.store = s
 	}
-	if invoiceMatch == nil {
-		return nil, fmt.Errorf("invoice not found in WWW-Authenticate header")
+}
+
+// New creates a new L402 client with the provided wallet for handling payments
+// and token store for storing L402 tokens.
+func New(w wallet.Wallet, s tokenstore.Store, opts ...Option) *Client {
+	t := NewTransport(w, s, nil)
+	c := &Client{
+		transport:  t,
+		httpClient: &http.Client{Transport: t},
 	}
-	if macaroonMatch == nil {
-		return nil, fmt.Errorf("macaroon not found in WWW-Authenticate header")
+	for _, opt := range opts {
+		opt(c)
 	}
-
-	// Extract header key, invoice, and macaroon from matches and return the Challenge struct
-	return &Challenge{
-		HeaderKey: headerKeyMatch,
-		Invoice:   invoiceMatch[1],
-		Macaroon:  macaroonMatch[1],
-	}, nil
+	return c
 }
 
-// constructL402Token constructs the L402 token from the given Challenge and preimage.
-func constructL402Token(challenge Challenge, preimage string) string {
-	// Construct and return the token using fmt.Sprintf for formatting
-	return fmt.Sprintf("%s %s:%s", challenge.HeaderKey, challenge.Macaroon, preimage)
+// Do makes an HTTP request and handles L402 payment challenges.
+// It automatically pays the invoice and retries the request with the L402 token if a 402 Payment Required response is received.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req)
 }
@@ -1,12 +1,21 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+	"github.com/stretchr/testify/require"
 	"github.com/sulusolutions/gol402/tokenstore"
 	"github.com/sulusolutions/gol402/wallet"
 )
@@ -22,6 +31,8 @@ func TestNewClient(t *testing.T) {
 }
 
 func TestMakeRequest(t *testing.T) {
+	challenge := `L402 macaroon="testMacaroon", invoice="` + newTestInvoice(t, 1000) + `"`
+
 	tests := []struct {
 		name          string
 		serverHandler func(w http.ResponseWriter, r *http.Request)
@@ -40,7 +51,7 @@ func TestMakeRequest(t *testing.T) {
 			name: "Handle 402 Payment Required with successful payment",
 			serverHandler: func(w http.ResponseWriter, r *http.Request) {
 				if r.Header.Get("Authorization") == "" {
-					w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="testInvoice"`)
+					w.Header().Set("WWW-Authenticate", challenge)
 					w.WriteHeader(http.StatusPaymentRequired)
 				} else {
 					w.WriteHeader(http.StatusOK)
@@ -51,7 +62,7 @@ func TestMakeRequest(t *testing.T) {
 		{
 			name: "Handle 402 Payment Required with payment failure",
 			serverHandler: func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="testInvoice"`)
+				w.Header().Set("WWW-Authenticate", challenge)
 				w.WriteHeader(http.StatusPaymentRequired)
 			},
 			walletErr: fmt.Errorf("payment error"),
@@ -127,3 +138,83 @@ func (ms *mockServer) Start() *httptest.Server {
 	server := httptest.NewServer(http.HandlerFunc(ms.HandlerFunc))
 	return server
 }
+
+// newTestInvoice encodes a signed regtest invoice for amount whose payment hash matches wallet.MockPreimage.
+func newTestInvoice(t *testing.T, amount lnwire.MilliSatoshi) string {
+	t.Helper()
+
+	preimage, err := lntypes.MakePreimageFromStr(wallet.MockPreimage)
+	require.NoError(t, err)
+
+	return encodeTestInvoice(t, amount, preimage.Hash())
+}
+
+// encodeTestInvoice encodes a signed regtest invoice for amount with the given payment hash.
+func encodeTestInvoice(t *testing.T, amount lnwire.MilliSatoshi, paymentHash lntypes.Hash) string {
+	t.Helper()
+
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	require.NoError(t, err)
+
+	inv, err := zpay32.NewInvoice(&chaincfg.RegressionNetParams, paymentHash, time.Now(),
+		zpay32.Amount(amount), zpay32.Description("test"))
+	require.NoError(t, err)
+
+	encoded, err := inv.Encode(zpay32.MessageSigner{
+		SignCompact: func(hash []byte) ([]byte, error) {
+			return btcec.SignCompact(btcec.S256(), privKey, hash, true)
+		},
+	})
+	require.NoError(t, err)
+
+	return encoded
+}
+
+// TestClientOptions verifies that functional options configure the underlying transport and http.Client.
+func TestClientOptions(t *testing.T) {
+	challenge := `L402 macaroon="testMacaroon", invoice="` + newTestInvoice(t, 1000) + `"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "test-agent" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", challenge)
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	base := &countingTransport{}
+	httpClient := &http.Client{Transport: base, Timeout: 5 * time.Second}
+	var logs bytes.Buffer
+
+	c := New(wallet.NewMockWallet(nil), tokenstore.NewNoopStore(),
+		WithHTTPClient(httpClient),
+		WithUserAgent("test-agent"),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Equal(t, 2, base.requests)
+	require.Equal(t, 5*time.Second, c.httpClient.Timeout)
+	require.Equal(t, base, httpClient.Transport, "the given http.Client must not be modified")
+	require.Contains(t, logs.String(), "L402 invoice paid")
+}
+
+// TestWithMaxRetries verifies that zero retries disables repurchases instead of using the default.
+func TestWithMaxRetries(t *testing.T) {
+	for n, want := range map[int]int{0: -1, -2: -1, 2: 2} {
+		c := New(wallet.NewMockWallet(nil), tokenstore.NewNoopStore(), WithMaxRetries(n))
+		require.Equal(t, want, c.transport.MaxRepurchases, "WithMaxRetries(%d)", n)
+	}
+}
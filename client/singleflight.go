@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/sulusolutions/gol402/tokenstore"
+)
+
+// paymentCall is a payment in flight or completed for a single key.
+type paymentCall struct {
+	done  chan struct{}
+	token tokenstore.Token
+	err   error
+
+	// canceled is set when the call failed because the context of its caller was done.
+	canceled bool
+}
+
+// paymentGroup makes sure only one payment per key is in flight at a time.
+// Callers arriving while a payment is in progress wait for it and share its result,
+// including its error. The zero value is ready to use.
+type paymentGroup struct {
+	mu    sync.Mutex
+	calls map[string]*paymentCall
+}
+
+// do runs fn with ctx unless a call for key is already in flight, in which case it waits for that
+// call and returns its result. Waiting stops early if ctx is done. If the call fails because the
+// context of its caller is done, the waiting callers do not share that error: once fn has returned,
+// one of them runs fn again with its own context. fn must therefore check whether the canceled call
+// left a token or a payment that may still settle before paying again.
+func (g *paymentGroup) do(ctx context.Context, key string, fn func(context.Context) (tokenstore.Token, error)) (tokenstore.Token, error) {
+	for {
+		g.mu.Lock()
+		if g.calls == nil {
+			g.calls = make(map[string]*paymentCall)
+		}
+		call, ok := g.calls[key]
+		if !ok {
+			break
+		}
+		g.mu.Unlock()
+
+		select {
+		case <-call.done:
+			if !call.canceled {
+				return call.token, call.err
+			}
+		case <-ctx.Done():
+			return tokenstore.Token{}, ctx.Err()
+		}
+	}
+
+	call := &paymentCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.token, call.err = fn(ctx)
+	call.canceled = call.err != nil && ctx.Err() != nil
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+
+	return call.token, call.err
+}
+
+// paymentKey returns the key payments for u are coordinated under.
+// It matches the host and path scope tokens are stored under.
+func paymentKey(u *url.URL) string {
+	return u.Host + u.Path
+}
@@ -0,0 +1,294 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/tokenstore"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// blockingWallet counts payments and holds them until unblock is closed.
+type blockingWallet struct {
+	mu       sync.Mutex
+	payments int
+	unblock  chan struct{}
+	err      error
+}
+
+func (bw *blockingWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	bw.mu.Lock()
+	bw.payments++
+	bw.mu.Unlock()
+
+	<-bw.unblock
+	return wallet.NewMockWallet(bw.err).PayInvoice(ctx, invoice)
+}
+
+// TestTransportConcurrentPayments verifies that concurrent requests for the same resource pay only once
+// and that the outcome of the payment is shared by all of them.
+func TestTransportConcurrentPayments(t *testing.T) {
+	const callers = 50
+
+	tests := []struct {
+		name       string
+		walletErr  error
+		wantStatus int
+	}{
+		{
+			name:       "Successful payment is shared",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:      "Payment failure fans out",
+			walletErr: errors.New("payment error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := newTestInvoice(t, 1000)
+
+			// Hold the payment until every caller has received its challenge.
+			var challenged sync.WaitGroup
+			challenged.Add(callers)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") == "" {
+					w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+					w.WriteHeader(http.StatusPaymentRequired)
+					challenged.Done()
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			w := &blockingWallet{unblock: make(chan struct{}), err: tt.walletErr}
+			c := New(w, tokenstore.NewInMemoryStore())
+
+			var wg sync.WaitGroup
+			errs := make(chan error, callers)
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL+"/resource", nil)
+					if err != nil {
+						errs <- err
+						return
+					}
+					resp, err := c.Do(req)
+					if err != nil {
+						errs <- err
+						return
+					}
+					resp.Body.Close()
+					if resp.StatusCode != tt.wantStatus {
+						errs <- errors.New(resp.Status)
+					}
+				}()
+			}
+
+			// Only release the payment once every other caller is waiting for it.
+			challenged.Wait()
+			require.Eventually(t, func() bool {
+				return waitingCallers() == callers-1
+			}, 5*time.Second, time.Millisecond)
+			close(w.unblock)
+			wg.Wait()
+			close(errs)
+
+			failures := 0
+			for err := range errs {
+				if tt.walletErr == nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				require.ErrorIs(t, err, tt.walletErr)
+				failures++
+			}
+			if tt.walletErr != nil {
+				require.Equal(t, callers, failures)
+			}
+			require.Equal(t, 1, w.payments)
+		})
+	}
+}
+
+// cancelingWallet fails the first payment once the context of its caller is done.
+type cancelingWallet struct {
+	mu       sync.Mutex
+	payments int
+}
+
+func (cw *cancelingWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	cw.mu.Lock()
+	cw.payments++
+	first := cw.payments == 1
+	cw.mu.Unlock()
+
+	if first {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return wallet.NewMockWallet(nil).PayInvoice(ctx, invoice)
+}
+
+func (cw *cancelingWallet) count() int {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return cw.payments
+}
+
+// TestTransportCanceledPayment verifies that a caller waiting on a payment does not fail with the
+// context error of the canceled caller making it, and does not pay again while that payment may still settle.
+func TestTransportCanceledPayment(t *testing.T) {
+	invoice := newTestInvoice(t, 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	w := &cancelingWallet{}
+	c := New(w, tokenstore.NewInMemoryStore())
+	do := func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/resource", nil)
+		if err != nil {
+			return nil, err
+		}
+		return c.Do(req)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := do(ctx)
+		firstErr <- err
+	}()
+	require.Eventually(t, func() bool { return w.count() == 1 }, 5*time.Second, time.Millisecond)
+
+	secondErr := make(chan error, 1)
+	go func() {
+		_, err := do(context.Background())
+		secondErr <- err
+	}()
+	require.Eventually(t, func() bool { return waitingCallers() == 1 }, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-firstErr, context.Canceled)
+
+	err := <-secondErr
+	require.ErrorIs(t, err, wallet.ErrPaymentPending)
+	require.NotErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, w.count())
+}
+
+// TestTransportCanceledBeforePayment verifies that a caller waiting on a payment takes it over
+// when the caller making it is canceled before the wallet is called.
+func TestTransportCanceledBeforePayment(t *testing.T) {
+	invoice := newTestInvoice(t, 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The first payment is held in approval until its caller is canceled.
+	var approvals sync.WaitGroup
+	approvals.Add(1)
+	var once sync.Once
+	approve := func(ctx context.Context, req *PaymentRequest) (Decision, error) {
+		first := false
+		once.Do(func() { first = true })
+		if first {
+			approvals.Done()
+			<-ctx.Done()
+			return Reject, ctx.Err()
+		}
+		return Approve, nil
+	}
+
+	w := &blockingWallet{unblock: make(chan struct{})}
+	close(w.unblock)
+	c := New(w, tokenstore.NewInMemoryStore(), WithApproval(approve))
+
+	do := func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/resource", nil)
+		if err != nil {
+			return nil, err
+		}
+		return c.Do(req)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := do(ctx)
+		firstErr <- err
+	}()
+	approvals.Wait()
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	second := make(chan result, 1)
+	go func() {
+		resp, err := do(context.Background())
+		second <- result{resp, err}
+	}()
+	require.Eventually(t, func() bool { return waitingCallers() == 1 }, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-firstErr, context.Canceled)
+
+	res := <-second
+	require.NoError(t, res.err)
+	res.resp.Body.Close()
+	require.Equal(t, http.StatusOK, res.resp.StatusCode)
+	require.Equal(t, 1, w.payments)
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return u
+}
+
+// waitingCallers returns the number of goroutines waiting in paymentGroup.do for a call run by
+// another caller. The caller running the call is blocked in the wallet instead.
+func waitingCallers() int {
+	buf := make([]byte, 16<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+
+	waiting := 0
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		header, _, _ := strings.Cut(stack, "\n")
+		if strings.Contains(header, "[select") && strings.Contains(stack, ".(*paymentGroup).do(") {
+			waiting++
+		}
+	}
+	return waiting
+}
@@ -0,0 +1,401 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sulusolutions/gol402/invoice"
+	"github.com/sulusolutions/gol402/macaroons"
+	"github.com/sulusolutions/gol402/tokenstore"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// DefaultMaxRepurchases is the default number of times a rejected token is replaced within a single request.
+const DefaultMaxRepurchases = 1
+
+var (
+	// ErrTokenRejected is returned when the server keeps answering 402 Payment Required to a token
+	// and the maximum number of repurchases has been reached. Rejected tokens are removed from the store.
+	ErrTokenRejected = errors.New("L402 token rejected by server")
+
+	// ErrPaymentFailed is returned, wrapping the wallet error, when paying an invoice fails.
+	ErrPaymentFailed = errors.New("payment failed")
+)
+
+// Transport is an http.RoundTripper that handles L402 payment challenges.
+// It attaches stored L402 tokens to outgoing requests and, when a 402 Payment Required
+// response is received, pays the invoice and retries the request with the new token.
+// It can be plugged into any *http.Client to add L402 support to existing SDKs.
+//
+// If the wallet reports a payment as pending with wallet.ErrPaymentPending, or gives up on it because
+// the request context is done, the request fails with wallet.ErrPaymentPending and the payment stays
+// counted against the Budget. Only payments that definitely failed are refunded to the Budget. Until the pending invoice expires,
+// no other invoice is paid for the same resource, so the pending payment cannot settle alongside a new one.
+type Transport struct {
+	// Base is the underlying RoundTripper used to send requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// MaxReplayBodySize is the maximum number of bytes of a request body buffered in memory so
+	// the request can be retried after payment. It only applies to requests without a GetBody func.
+	// Zero means DefaultMaxReplayBodySize; a negative value disables buffering.
+	MaxReplayBodySize int64
+
+	// Budget, if set, limits how much is paid for invoices. Payments that would exceed
+	// it fail with ErrBudgetExceeded before the wallet is called.
+	Budget *Budget
+
+	// MaxRepurchases is the maximum number of times a token rejected by the server is replaced by
+	// paying a new invoice within a single request. Zero means DefaultMaxRepurchases; a negative
+	// value disables repurchasing, so a rejected token fails the request with ErrTokenRejected.
+	MaxRepurchases int
+
+	// PreferredSchemes lists the challenge schemes the transport pays for, most preferred first.
+	// If empty, DefaultPreferredSchemes is used.
+	PreferredSchemes []string
+
+	// Approve, if set, is called before each payment and can approve, reject or defer it.
+	Approve ApprovalFunc
+
+	// UserAgent, if set, is sent as the User-Agent header of requests that do not already have one.
+	UserAgent string
+
+	// Logger, if set, receives payment and token lifecycle events.
+	Logger *slog.Logger
+
+	// TokenTTL, if set, is how long tokens are assumed to remain valid when their macaroon
+	// does not carry an expiry. Expired tokens are replaced before they are sent.
+	TokenTTL time.Duration
+
+	wallet   wallet.Wallet
+	store    tokenstore.ContextStore
+	payments paymentGroup
+
+	mu      sync.Mutex
+	pending map[string]*invoice.Invoice // Payment key to the invoice of a payment that is still pending
+}
+
+// NewTransport creates a new L402 transport with the provided wallet for handling payments
+// and token store for storing L402 tokens. Requests are sent through base, or
+// http.DefaultTransport if base is nil.
+func NewTransport(w wallet.Wallet, s tokenstore.Store, base http.RoundTripper) *Transport {
+	return NewContextTransport(w, tokenstore.NewContextStore(s), base)
+}
+
+// NewContextTransport is like NewTransport but takes a context-aware token store,
+// which receives the context of each request.
+func NewContextTransport(w wallet.Wallet, s tokenstore.ContextStore, base http.RoundTripper) *Transport {
+	return &Transport{
+		Base:   base,
+		wallet: w,
+		store:  s,
+	}
+}
+
+// RoundTrip implements http.RoundTripper. The given request is never modified;
+// a clone carrying the Authorization header is sent instead.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	outReq := req.Clone(ctx)
+
+	replayable, err := prepareBody(outReq, t.maxReplayBodySize())
+	if err != nil {
+		return nil, err
+	}
+
+	if t.UserAgent != "" && outReq.Header.Get("User-Agent") == "" {
+		outReq.Header.Set("User-Agent", t.UserAgent)
+	}
+
+	// Try to retrieve and use L402 token if available.
+	// Stored tokens already carry their scheme (e.g. "L402 macaroon:preimage").
+	l402Token, _ := t.storedToken(ctx, req.URL)
+
+	repurchases := 0
+	for attempt := 0; ; attempt++ {
+		attemptReq := outReq
+		if attempt > 0 {
+			// Prepare a copy of the original request for retrying with the new L402 token
+			attemptReq = outReq.Clone(ctx)
+			attemptReq.Body, err = replayBody(outReq)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if l402Token.Value != "" {
+			attemptReq.Header.Set("Authorization", l402Token.Value)
+		}
+
+		response, err := t.base().RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode != http.StatusPaymentRequired {
+			return response, nil
+		}
+
+		// The challenge lives in the headers, so the body of the 402 response is no longer needed.
+		authHeaders := response.Header.Values("WWW-Authenticate")
+		response.Body.Close()
+
+		// A 402 in response to a token means the server no longer accepts it,
+		// e.g. because it expired, was revoked, or the price changed.
+		if l402Token.Value != "" {
+			t.invalidate(ctx, req.URL, l402Token)
+			t.logger().Warn("L402 token rejected", "host", req.URL.Host, "path", req.URL.Path)
+
+			if repurchases >= t.maxRepurchases() {
+				return nil, fmt.Errorf("%w after %d repurchases", ErrTokenRejected, repurchases)
+			}
+			repurchases++
+		}
+
+		if !replayable {
+			return nil, ErrBodyNotReplayable
+		}
+
+		l402Token, err = t.handlePaymentChallenge(ctx, req.URL, l402Token, authHeaders)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// handlePaymentChallenge handles the 402 Payment Required response by obtaining a new token for u.
+// Concurrent challenges for the same resource share a single payment.
+func (t *Transport) handlePaymentChallenge(ctx context.Context, u *url.URL, sentToken tokenstore.Token, authHeaders []string) (tokenstore.Token, error) {
+	challenge, err := parseHeader(authHeaders, t.preferredSchemes())
+	if err != nil {
+		return tokenstore.Token{}, err
+	}
+
+	return t.payments.do(ctx, paymentKey(u), func(ctx context.Context) (tokenstore.Token, error) {
+		// Another request may have bought a token since this one was sent, or left a payment
+		// that may still settle, e.g. because it was canceled while this one was waiting for it.
+		if token, ok := t.storedToken(ctx, u); ok && token.Value != sentToken.Value {
+			t.logger().Debug("Reusing L402 token purchased by another request", "host", u.Host, "path", u.Path)
+			return token, nil
+		}
+		if pending, ok := t.pendingPayment(u); ok {
+			return tokenstore.Token{}, fmt.Errorf("%w: invoice %s for %s%s", wallet.ErrPaymentPending,
+				pending.PaymentHash, u.Host, u.Path)
+		}
+		return t.pay(ctx, u, challenge)
+	})
+}
+
+// storedToken returns the token stored for u, unless it has expired.
+// A store that fails is treated as empty, so the request goes ahead without a token.
+func (t *Transport) storedToken(ctx context.Context, u *url.URL) (tokenstore.Token, bool) {
+	token, ok, err := t.store.Get(ctx, u)
+	if err != nil {
+		t.logger().Warn("Unable to read L402 token", "host", u.Host, "path", u.Path, "error", err)
+		return tokenstore.Token{}, false
+	}
+	if !ok || token.Expired(time.Now()) {
+		return tokenstore.Token{}, false
+	}
+	return token, true
+}
+
+// invalidate removes a rejected token from the store, unless it has already been replaced.
+// The token may have been found by prefix or caveats, so it is deleted where it is stored.
+func (t *Transport) invalidate(ctx context.Context, u *url.URL, rejected tokenstore.Token) {
+	record := tokenstore.Record{Host: u.Host, Path: u.Path}
+	var found bool
+	if m, ok := t.store.(tokenstore.Matcher); ok {
+		record, found, _ = m.Match(ctx, u)
+	} else {
+		record.Token, found, _ = t.store.Get(ctx, u)
+	}
+	if !found || record.Token.Value != rejected.Value {
+		return
+	}
+
+	stored := &url.URL{Scheme: u.Scheme, Host: record.Host, Path: record.Path}
+	if err := t.store.Delete(ctx, stored); err != nil {
+		t.logger().Warn("Unable to delete rejected L402 token", "host", record.Host, "path", record.Path, "error", err)
+	}
+}
+
+// pay pays the invoice of the challenge, verifies the payment, and stores the resulting L402 token for u.
+func (t *Transport) pay(ctx context.Context, u *url.URL, challenge *Challenge) (tokenstore.Token, error) {
+	decoded, err := wallet.Invoice(challenge.Invoice).Decode()
+	if err != nil {
+		return tokenstore.Token{}, err
+	}
+
+	var reserved *reservation
+	if t.Budget != nil {
+		if !decoded.HasAmount() {
+			return tokenstore.Token{}, fmt.Errorf("%w: invoice does not specify an amount", ErrBudgetExceeded)
+		}
+		reserved, err = t.Budget.reserve(u.Host, decoded.Amount)
+		if err != nil {
+			return tokenstore.Token{}, err
+		}
+	}
+
+	if t.Approve != nil {
+		decision, err := t.Approve(ctx, &PaymentRequest{
+			URL:         u,
+			Amount:      decoded.Amount,
+			Description: decoded.Description,
+			Services:    macaroonServices(challenge.Macaroon),
+			Invoice:     decoded,
+			Challenge:   challenge,
+		})
+		switch {
+		case err != nil:
+			reserved.release()
+			return tokenstore.Token{}, fmt.Errorf("payment approval failed: %w", err)
+		case decision == Defer:
+			reserved.release()
+			return tokenstore.Token{}, ErrPaymentDeferred
+		case decision != Approve:
+			reserved.release()
+			return tokenstore.Token{}, ErrPaymentRejected
+		}
+	}
+
+	// Pay the invoice using the wallet
+	logger := t.logger().With("host", u.Host, "path", u.Path,
+		"amount_msat", uint64(decoded.Amount), "payment_hash", decoded.PaymentHash.String())
+	logger.Info("Paying L402 invoice")
+
+	paymentResult, err := t.wallet.PayInvoice(ctx, wallet.Invoice(challenge.Invoice))
+	if err != nil && paymentMaySettle(err) {
+		// The payment may still settle, so it stays counted and no other invoice is paid for u meanwhile.
+		t.setPending(u, decoded)
+		logger.Warn("L402 payment pending", "error", err)
+		if !errors.Is(err, wallet.ErrPaymentPending) {
+			err = fmt.Errorf("%w: %w", wallet.ErrPaymentPending, err)
+		}
+		return tokenstore.Token{}, err
+	}
+	if err != nil {
+		reserved.release()
+		logger.Error("L402 payment failed", "error", err)
+		return tokenstore.Token{}, fmt.Errorf("%w: %w", ErrPaymentFailed, err)
+	}
+
+	// The payment went through, so the reservation is kept, adjusted for the routing fee.
+	reserved.settle(decoded.Amount + paymentResult.Fee)
+
+	// Make sure the wallet actually paid this invoice before building a token from its preimage.
+	if err := verifyPreimage(paymentResult.Preimage, decoded, challenge.Macaroon); err != nil {
+		logger.Error("L402 payment returned an invalid preimage", "error", err)
+		return tokenstore.Token{}, err
+	}
+	logger.Info("L402 invoice paid", "fee_msat", uint64(paymentResult.Fee))
+
+	// Construct L402 token using the challenge details and the preimage from the payment result
+	l402Token := tokenstore.Token{
+		Value:       constructL402Token(*challenge, paymentResult.Preimage),
+		CreatedAt:   time.Now(),
+		ExpiresAt:   t.tokenExpiry(challenge.Macaroon),
+		AmountPaid:  decoded.Amount,
+		PaymentHash: decoded.PaymentHash,
+	}
+	if err := t.store.Put(ctx, u, l402Token); err != nil {
+		// The token is still used for this request, but will have to be bought again.
+		logger.Error("Unable to store L402 token", "error", err)
+	}
+
+	return l402Token, nil
+}
+
+// paymentMaySettle reports whether a payment that failed with err may still settle: the wallet
+// reported it as pending, or stopped waiting for it because the context was done.
+func paymentMaySettle(err error) bool {
+	return errors.Is(err, wallet.ErrPaymentPending) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// setPending records that the payment of inv for u is pending.
+func (t *Transport) setPending(u *url.URL, inv *invoice.Invoice) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending == nil {
+		t.pending = make(map[string]*invoice.Invoice)
+	}
+	t.pending[paymentKey(u)] = inv
+}
+
+// pendingPayment returns the invoice of a payment for u that is still pending.
+// Payments are considered pending until their invoice expires.
+func (t *Transport) pendingPayment(u *url.URL) (*invoice.Invoice, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	inv, ok := t.pending[paymentKey(u)]
+	if !ok {
+		return nil, false
+	}
+	if inv.Expired(time.Now()) {
+		delete(t.pending, paymentKey(u))
+		return nil, false
+	}
+	return inv, true
+}
+
+// tokenExpiry returns when a token for the macaroon expires: the earliest valid_until caveat,
+// otherwise TokenTTL from now, otherwise the zero time.
+func (t *Transport) tokenExpiry(encodedMacaroon string) time.Time {
+	if mac, err := macaroons.Decode(encodedMacaroon); err == nil {
+		if expiry, ok := macaroons.ValidUntil(mac); ok {
+			return expiry
+		}
+	}
+	if t.TokenTTL > 0 {
+		return time.Now().Add(t.TokenTTL)
+	}
+	return time.Time{}
+}
+
+// discardLogger is used when no Logger is configured.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func (t *Transport) logger() *slog.Logger {
+	if t.Logger == nil {
+		return discardLogger
+	}
+	return t.Logger
+}
+
+func (t *Transport) preferredSchemes() []string {
+	if len(t.PreferredSchemes) == 0 {
+		return DefaultPreferredSchemes
+	}
+	return t.PreferredSchemes
+}
+
+func (t *Transport) maxRepurchases() int {
+	if t.MaxRepurchases == 0 {
+		return DefaultMaxRepurchases
+	}
+	return t.MaxRepurchases
+}
+
+func (t *Transport) maxReplayBodySize() int64 {
+	if t.MaxReplayBodySize == 0 {
+		return DefaultMaxReplayBodySize
+	}
+	return t.MaxReplayBodySize
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
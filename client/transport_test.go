@@ -0,0 +1,492 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/macaroons"
+	"github.com/sulusolutions/gol402/tokenstore"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// countingTransport records how many requests were sent through it.
+type countingTransport struct {
+	requests int
+}
+
+func (ct *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// TestTransportWithHTTPClient verifies that the transport handles a payment challenge when used by a plain http.Client.
+func TestTransportWithHTTPClient(t *testing.T) {
+	invoice := newTestInvoice(t, 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "L402 testMacaroon:"+wallet.MockPreimage {
+			w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	base := &countingTransport{}
+	store := tokenstore.NewInMemoryStore()
+	httpClient := &http.Client{Transport: NewTransport(wallet.NewMockWallet(nil), store, base)}
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL+"/resource", nil)
+	require.NoError(t, err)
+
+	resp, err := httpClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, base.requests)
+	require.Empty(t, req.Header.Get("Authorization"), "original request must not be modified")
+
+	// The stored token should be reused without paying again.
+	resp, err = httpClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 3, base.requests)
+}
+
+// TestTransportPaymentError verifies that wallet errors are returned from RoundTrip.
+func TestTransportPaymentError(t *testing.T) {
+	invoice := newTestInvoice(t, 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+		w.WriteHeader(http.StatusPaymentRequired)
+	}))
+	defer server.Close()
+
+	errPayment := errors.New("payment error")
+	transport := NewTransport(wallet.NewMockWallet(errPayment), tokenstore.NewNoopStore(), nil)
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = transport.RoundTrip(req)
+	require.ErrorIs(t, err, errPayment)
+}
+
+// pendingWallet reports every payment as pending.
+type pendingWallet struct {
+	mu       sync.Mutex
+	payments int
+}
+
+func (pw *pendingWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.payments++
+	return nil, fmt.Errorf("payment not settled: %w", wallet.ErrPaymentPending)
+}
+
+// TestTransportPendingPayment verifies that a pending payment stays counted against the budget
+// and that no other invoice is paid for the resource while it is pending.
+func TestTransportPendingPayment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+newTestInvoice(t, 1000)+`"`)
+		w.WriteHeader(http.StatusPaymentRequired)
+	}))
+	defer server.Close()
+
+	w := &pendingWallet{}
+	budget := NewBudget(BudgetLimits{})
+	transport := NewTransport(w, tokenstore.NewInMemoryStore(), nil)
+	transport.Budget = budget
+
+	get := func(path string) error {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL+path, nil)
+		require.NoError(t, err)
+		_, err = transport.RoundTrip(req)
+		return err
+	}
+
+	err := get("/resource")
+	require.ErrorIs(t, err, wallet.ErrPaymentPending)
+	require.NotErrorIs(t, err, ErrPaymentFailed)
+	require.Equal(t, lnwire.MilliSatoshi(1000), budget.Spent("", day))
+
+	// Another challenge for the same resource is not paid while the first payment is pending.
+	require.ErrorIs(t, get("/resource"), wallet.ErrPaymentPending)
+	require.Equal(t, 1, w.payments)
+	require.Equal(t, lnwire.MilliSatoshi(1000), budget.Spent("", day))
+
+	// Other resources are not affected.
+	require.ErrorIs(t, get("/other"), wallet.ErrPaymentPending)
+	require.Equal(t, 2, w.payments)
+}
+
+// TestTransportReplaysRequest verifies that the retry after payment carries the original body and headers.
+func TestTransportReplaysRequest(t *testing.T) {
+	const payload = `{"hello":"world"}`
+
+	tests := []struct {
+		name      string
+		body      func() io.Reader
+		maxSize   int64
+		wantError error
+	}{
+		{
+			name: "Body with GetBody",
+			body: func() io.Reader { return strings.NewReader(payload) },
+		},
+		{
+			name: "Body buffered by transport",
+			body: func() io.Reader { return io.NopCloser(strings.NewReader(payload)) },
+		},
+		{
+			name:      "Body exceeding buffer limit",
+			body:      func() io.Reader { return io.NopCloser(strings.NewReader(payload)) },
+			maxSize:   4,
+			wantError: ErrBodyNotReplayable,
+		},
+		{
+			name:      "Buffering disabled",
+			body:      func() io.Reader { return io.NopCloser(strings.NewReader(payload)) },
+			maxSize:   -1,
+			wantError: ErrBodyNotReplayable,
+		},
+	}
+
+	invoice := newTestInvoice(t, 1000)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil || string(body) != payload {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Custom") != "value" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if r.Header.Get("Authorization") == "" {
+					w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+					w.WriteHeader(http.StatusPaymentRequired)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			transport := NewTransport(wallet.NewMockWallet(nil), tokenstore.NewNoopStore(), nil)
+			transport.MaxReplayBodySize = tt.maxSize
+
+			req, err := http.NewRequestWithContext(context.Background(), "POST", server.URL, tt.body())
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Custom", "value")
+
+			resp, err := transport.RoundTrip(req)
+			if tt.wantError != nil {
+				require.ErrorIs(t, err, tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+// TestTransportTokenLifecycle verifies that rejected tokens are removed and repurchased a bounded number of times.
+func TestTransportTokenLifecycle(t *testing.T) {
+	invoice := newTestInvoice(t, 1000)
+	validToken := "L402 fresh:" + wallet.MockPreimage
+
+	tests := []struct {
+		name           string
+		storedToken    string
+		acceptTokens   bool
+		maxRepurchases int
+		walletErr      error
+		wantPayments   int
+		wantError      error
+		wantStored     bool
+	}{
+		{
+			name:         "Stale token is replaced",
+			storedToken:  "L402 stale:0000",
+			acceptTokens: true,
+			wantPayments: 1,
+			wantStored:   true,
+		},
+		{
+			name:         "Purchased token rejected",
+			wantPayments: 2,
+			wantError:    ErrTokenRejected,
+		},
+		{
+			name:           "Repurchases bounded by option",
+			storedToken:    "L402 stale:0000",
+			maxRepurchases: 3,
+			wantPayments:   3,
+			wantError:      ErrTokenRejected,
+		},
+		{
+			name:           "Repurchasing disabled",
+			storedToken:    "L402 stale:0000",
+			acceptTokens:   true,
+			maxRepurchases: -1,
+			wantError:      ErrTokenRejected,
+		},
+		{
+			name:         "Payment failure after rejection",
+			storedToken:  "L402 stale:0000",
+			walletErr:    errors.New("payment error"),
+			wantPayments: 1,
+			wantError:    ErrPaymentFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.acceptTokens && r.Header.Get("Authorization") == validToken {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `L402 macaroon="fresh", invoice="`+invoice+`"`)
+				w.WriteHeader(http.StatusPaymentRequired)
+			}))
+			defer server.Close()
+
+			u := mustParseURL(t, server.URL+"/resource")
+			store := tokenstore.NewInMemoryStore()
+			if tt.storedToken != "" {
+				require.NoError(t, store.Put(u, tokenstore.NewToken(tt.storedToken)))
+			}
+
+			w := &blockingWallet{unblock: make(chan struct{}), err: tt.walletErr}
+			close(w.unblock)
+			transport := NewTransport(w, store, nil)
+			transport.MaxRepurchases = tt.maxRepurchases
+
+			req, err := http.NewRequestWithContext(context.Background(), "GET", u.String(), nil)
+			require.NoError(t, err)
+
+			resp, err := transport.RoundTrip(req)
+			if tt.wantError != nil {
+				require.ErrorIs(t, err, tt.wantError)
+			} else {
+				require.NoError(t, err)
+				resp.Body.Close()
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			}
+			require.Equal(t, tt.wantPayments, w.payments)
+
+			stored, ok := store.Get(u)
+			require.Equal(t, tt.wantStored, ok)
+			if tt.wantStored {
+				require.Equal(t, validToken, stored.Value)
+			}
+		})
+	}
+}
+
+// TestTransportRejectedPrefixToken verifies that a rejected token found by prefix is deleted
+// where it is stored, so later requests under the prefix do not send it again.
+func TestTransportRejectedPrefixToken(t *testing.T) {
+	invoice := newTestInvoice(t, 1000)
+	validToken := "L402 fresh:" + wallet.MockPreimage
+
+	var staleSent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case validToken:
+			w.WriteHeader(http.StatusOK)
+			return
+		case "L402 stale:0000":
+			staleSent++
+		}
+		w.Header().Set("WWW-Authenticate", `L402 macaroon="fresh", invoice="`+invoice+`"`)
+		w.WriteHeader(http.StatusPaymentRequired)
+	}))
+	defer server.Close()
+
+	store := tokenstore.NewInMemoryStore()
+	require.NoError(t, store.Put(mustParseURL(t, server.URL+"/api"), tokenstore.NewToken("L402 stale:0000")))
+
+	transport := NewTransport(wallet.NewMockWallet(nil), store, nil)
+	transport.MaxRepurchases = -1
+
+	// The stale token is sent once, rejected and removed.
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL+"/api/a", nil)
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	require.ErrorIs(t, err, ErrTokenRejected)
+
+	records, err := store.List(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	for _, path := range []string{"/api/b", "/api/c"} {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	require.Equal(t, 1, staleSent)
+}
+
+// TestTransportTokenExpiry verifies that expired tokens are not sent and that purchased tokens carry their metadata.
+func TestTransportTokenExpiry(t *testing.T) {
+	preimage, err := lntypes.MakePreimageFromStr(wallet.MockPreimage)
+	require.NoError(t, err)
+	invoice := newTestInvoice(t, 1000)
+
+	validUntil := time.Now().Add(time.Hour).Truncate(time.Second)
+	mac, err := macaroons.Decode(newTestMacaroon(t, preimage.Hash()))
+	require.NoError(t, err)
+	require.NoError(t, macaroons.AddCaveats(mac, macaroons.NewValidUntilCaveat("svc", validUntil)))
+	expiringMac, err := macaroons.Encode(mac)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		macaroon   string
+		ttl        time.Duration
+		wantExpiry func(t *testing.T, expiry time.Time)
+	}{
+		{
+			name:     "No expiry",
+			macaroon: newTestMacaroon(t, preimage.Hash()),
+			wantExpiry: func(t *testing.T, expiry time.Time) {
+				require.True(t, expiry.IsZero())
+			},
+		},
+		{
+			name:     "Expiry from TTL",
+			macaroon: newTestMacaroon(t, preimage.Hash()),
+			ttl:      time.Hour,
+			wantExpiry: func(t *testing.T, expiry time.Time) {
+				require.WithinDuration(t, time.Now().Add(time.Hour), expiry, time.Minute)
+			},
+		},
+		{
+			name:     "Expiry from caveat",
+			macaroon: expiringMac,
+			ttl:      time.Minute,
+			wantExpiry: func(t *testing.T, expiry time.Time) {
+				require.Equal(t, validUntil, expiry)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var staleSent bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch auth := r.Header.Get("Authorization"); {
+				case auth == "L402 stale:0000":
+					staleSent = true
+				case auth != "":
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `L402 macaroon="`+tt.macaroon+`", invoice="`+invoice+`"`)
+				w.WriteHeader(http.StatusPaymentRequired)
+			}))
+			defer server.Close()
+
+			u := mustParseURL(t, server.URL+"/resource")
+			store := tokenstore.NewInMemoryStore()
+			require.NoError(t, store.Put(u, tokenstore.Token{
+				Value:     "L402 stale:0000",
+				ExpiresAt: time.Now().Add(-time.Minute),
+			}))
+
+			transport := NewTransport(wallet.NewMockWallet(nil), store, nil)
+			transport.TokenTTL = tt.ttl
+
+			req, err := http.NewRequestWithContext(context.Background(), "GET", u.String(), nil)
+			require.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.False(t, staleSent)
+
+			stored, ok := store.Get(u)
+			require.True(t, ok)
+			require.WithinDuration(t, time.Now(), stored.CreatedAt, time.Minute)
+			require.Equal(t, lnwire.MilliSatoshi(1000), stored.AmountPaid)
+			require.Equal(t, preimage.Hash(), stored.PaymentHash)
+			tt.wantExpiry(t, stored.ExpiresAt)
+		})
+	}
+}
+
+// ctxKey is used to check that the request context reaches the token store.
+type ctxKey struct{}
+
+// failingContextStore is a context-aware token store whose reads and writes fail.
+type failingContextStore struct {
+	tokenstore.ContextStore
+	seen []any
+}
+
+func (s *failingContextStore) Get(ctx context.Context, u *url.URL) (tokenstore.Token, bool, error) {
+	s.seen = append(s.seen, ctx.Value(ctxKey{}))
+	return tokenstore.Token{}, false, errors.New("store offline")
+}
+
+func (s *failingContextStore) Put(ctx context.Context, u *url.URL, token tokenstore.Token) error {
+	s.seen = append(s.seen, ctx.Value(ctxKey{}))
+	return errors.New("store offline")
+}
+
+// TestTransportContextStore verifies that context-aware stores receive the request context
+// and that store failures do not fail requests.
+func TestTransportContextStore(t *testing.T) {
+	challenge := `L402 macaroon="testMacaroon", invoice="` + newTestInvoice(t, 1000) + `"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", challenge)
+			w.WriteHeader(http.StatusPaymentRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := &failingContextStore{}
+	c := New(wallet.NewMockWallet(nil), tokenstore.NewNoopStore(), WithContextStore(store))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NotEmpty(t, store.seen)
+	for _, value := range store.seen {
+		require.Equal(t, "request", value)
+	}
+}
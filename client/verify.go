@@ -0,0 +1,48 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/sulusolutions/gol402/invoice"
+	"github.com/sulusolutions/gol402/macaroons"
+)
+
+// ErrInvalidPreimage is returned when the preimage returned by the wallet does not match
+// the payment hash of the invoice or of the macaroon. The resulting token is neither stored nor sent.
+var ErrInvalidPreimage = errors.New("invalid payment preimage")
+
+// verifyPreimage checks that preimage hashes to the payment hash of inv and, when the macaroon
+// carries an L402 identifier, to the payment hash committed to by the macaroon.
+func verifyPreimage(preimage string, inv *invoice.Invoice, encodedMacaroon string) error {
+	p, err := lntypes.MakePreimageFromStr(preimage)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPreimage, err)
+	}
+
+	if !p.Matches(inv.PaymentHash) {
+		return fmt.Errorf("%w: preimage does not match invoice payment hash %v", ErrInvalidPreimage, inv.PaymentHash)
+	}
+
+	if hash, ok := macaroonPaymentHash(encodedMacaroon); ok && !p.Matches(hash) {
+		return fmt.Errorf("%w: preimage does not match macaroon payment hash %v", ErrInvalidPreimage, hash)
+	}
+
+	return nil
+}
+
+// macaroonPaymentHash extracts the payment hash from the identifier of a base64 encoded L402 macaroon.
+// It returns false if the macaroon cannot be decoded or does not use the L402 identifier format.
+func macaroonPaymentHash(encoded string) (lntypes.Hash, bool) {
+	mac, err := macaroons.Decode(encoded)
+	if err != nil {
+		return lntypes.Hash{}, false
+	}
+
+	id, err := macaroons.DecodeIdentifier(mac.Id())
+	if err != nil {
+		return lntypes.Hash{}, false
+	}
+	return id.PaymentHash, true
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/invoice"
+	"github.com/sulusolutions/gol402/macaroons"
+	"github.com/sulusolutions/gol402/tokenstore"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// newTestMacaroon returns a base64 encoded macaroon with an L402 identifier committing to paymentHash.
+func newTestMacaroon(t *testing.T, paymentHash lntypes.Hash) string {
+	t.Helper()
+
+	rootKeys, err := macaroons.NewStaticRootKeyStore([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	mac, err := macaroons.Mint(context.Background(), rootKeys, paymentHash, "test")
+	require.NoError(t, err)
+
+	encoded, err := macaroons.Encode(mac)
+	require.NoError(t, err)
+	return encoded
+}
+
+func TestVerifyPreimage(t *testing.T) {
+	preimage, err := lntypes.MakePreimageFromStr(wallet.MockPreimage)
+	require.NoError(t, err)
+	otherHash := lntypes.Hash(sha256.Sum256([]byte("other")))
+
+	tests := []struct {
+		name      string
+		preimage  string
+		hash      lntypes.Hash
+		macaroon  string
+		wantError bool
+	}{
+		{
+			name:     "Matching invoice with opaque macaroon",
+			preimage: wallet.MockPreimage,
+			hash:     preimage.Hash(),
+			macaroon: "testMacaroon",
+		},
+		{
+			name:     "Matching invoice and macaroon",
+			preimage: wallet.MockPreimage,
+			hash:     preimage.Hash(),
+			macaroon: newTestMacaroon(t, preimage.Hash()),
+		},
+		{
+			name:      "Invoice mismatch",
+			preimage:  wallet.MockPreimage,
+			hash:      otherHash,
+			macaroon:  "testMacaroon",
+			wantError: true,
+		},
+		{
+			name:      "Macaroon mismatch",
+			preimage:  wallet.MockPreimage,
+			hash:      preimage.Hash(),
+			macaroon:  newTestMacaroon(t, otherHash),
+			wantError: true,
+		},
+		{
+			name:      "Empty preimage",
+			hash:      preimage.Hash(),
+			wantError: true,
+		},
+		{
+			name:      "Malformed preimage",
+			preimage:  "12345abcd",
+			hash:      preimage.Hash(),
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyPreimage(tt.preimage, &invoice.Invoice{PaymentHash: tt.hash}, tt.macaroon)
+			if tt.wantError {
+				require.ErrorIs(t, err, ErrInvalidPreimage)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestTransportRejectsInvalidPreimage verifies that a token built from a wrong preimage is neither sent nor stored.
+func TestTransportRejectsInvalidPreimage(t *testing.T) {
+	invoice := encodeTestInvoice(t, 1000, lntypes.Hash(sha256.Sum256([]byte("other"))))
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("WWW-Authenticate", `L402 macaroon="testMacaroon", invoice="`+invoice+`"`)
+		w.WriteHeader(http.StatusPaymentRequired)
+	}))
+	defer server.Close()
+
+	store := tokenstore.NewInMemoryStore()
+	c := New(wallet.NewMockWallet(nil), store)
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = c.Do(req)
+	require.ErrorIs(t, err, ErrInvalidPreimage)
+	require.Equal(t, 1, requests)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	_, ok := store.Get(u)
+	require.False(t, ok)
+}
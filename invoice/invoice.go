@@ -0,0 +1,192 @@
+// Package invoice decodes BOLT11 Lightning payment requests, so callers can inspect what they
+// are about to pay without a round-trip to a Lightning node.
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// ErrUnknownNetwork is returned when the invoice prefix does not match a known network.
+var ErrUnknownNetwork = errors.New("unknown invoice network prefix")
+
+// Invoice holds the decoded fields of a BOLT11 payment request.
+type Invoice struct {
+	// PaymentRequest is the encoded invoice this was decoded from.
+	PaymentRequest string
+
+	// Network is the name of the network the invoice is for:
+	// "mainnet", "testnet", "signet", "regtest" or "simnet".
+	Network string
+
+	// Amount is the amount requested by the invoice, or zero if the invoice does not specify one.
+	Amount lnwire.MilliSatoshi
+
+	// PaymentHash is the hash of the preimage revealed when the invoice is paid.
+	PaymentHash lntypes.Hash
+
+	// PaymentAddr is the payment secret, if the invoice includes one.
+	PaymentAddr *[32]byte
+
+	// Description is the purpose of the invoice. It is empty if the invoice
+	// commits to a DescriptionHash instead.
+	Description string
+
+	// DescriptionHash is the SHA256 hash of a description, if the invoice includes one.
+	DescriptionHash *[32]byte
+
+	// Payee is the public key of the node that created the invoice. It is taken from
+	// the invoice if present, and otherwise recovered from the signature.
+	Payee *btcec.PublicKey
+
+	// Timestamp is the time the invoice was created.
+	Timestamp time.Time
+
+	// Expiry is how long after Timestamp the invoice stays valid.
+	Expiry time.Duration
+
+	// MinFinalCLTVExpiry is the CLTV delta required for the final hop.
+	MinFinalCLTVExpiry uint64
+
+	// FallbackAddr is an on-chain fallback address, or empty if none is given.
+	FallbackAddr string
+
+	// RouteHints holds private routes to reach the payee.
+	RouteHints [][]zpay32.HopHint
+
+	// Features holds the feature bits signalled by the payee, if any.
+	Features *lnwire.FeatureVector
+}
+
+// network associates a network name with its chain parameters and BOLT11 prefix.
+type network struct {
+	name   string
+	hrp    string // Human-readable part of invoices after "ln"
+	params *chaincfg.Params
+}
+
+// signetParams holds the signet address parameters. The pinned btcd predates signet, but signet
+// addresses are encoded like testnet3 addresses, so they are copied from TestNet3Params.
+var signetParams = func() chaincfg.Params {
+	params := chaincfg.TestNet3Params
+	params.Name = "signet"
+	return params
+}()
+
+// networks lists the supported networks. Longer prefixes come first,
+// since e.g. regtest invoices ("lnbcrt") also start with the mainnet prefix ("lnbc").
+var networks = []network{
+	{"regtest", "bcrt", &chaincfg.RegressionNetParams},
+	{"mainnet", "bc", &chaincfg.MainNetParams},
+	{"signet", "tbs", &signetParams},
+	{"testnet", "tb", &chaincfg.TestNet3Params},
+	{"simnet", "sb", &chaincfg.SimNetParams},
+}
+
+// invoiceParams returns the parameters zpay32 decodes the invoices of the network with. zpay32 takes
+// the invoice prefix from Bech32HRPSegwit, so for signet, whose invoice prefix differs from its
+// address prefix, only that field is overridden. Addresses must be rendered with n.params instead.
+func (n network) invoiceParams() *chaincfg.Params {
+	if n.params.Bech32HRPSegwit == n.hrp {
+		return n.params
+	}
+	params := *n.params
+	params.Bech32HRPSegwit = n.hrp
+	return &params
+}
+
+// Decode parses a BOLT11 payment request, verifies its checksum and signature,
+// and returns its fields. The network is detected from the invoice prefix.
+func Decode(paymentRequest string) (*Invoice, error) {
+	lower := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(paymentRequest)), "lightning:")
+
+	for _, net := range networks {
+		if !strings.HasPrefix(lower, "ln"+net.hrp) {
+			continue
+		}
+
+		decoded, err := zpay32.Decode(lower, net.invoiceParams())
+		if err != nil {
+			return nil, fmt.Errorf("error decoding invoice: %w", err)
+		}
+
+		return fromZpay32(lower, net, decoded), nil
+	}
+
+	return nil, ErrUnknownNetwork
+}
+
+// ExpiresAt returns the time after which the invoice can no longer be paid.
+func (i *Invoice) ExpiresAt() time.Time {
+	return i.Timestamp.Add(i.Expiry)
+}
+
+// Expired reports whether the invoice has expired at the given time.
+func (i *Invoice) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt())
+}
+
+// HasAmount reports whether the invoice specifies an amount.
+func (i *Invoice) HasAmount() bool {
+	return i.Amount != 0
+}
+
+// PayeeHex returns the payee public key as a compressed hex string.
+func (i *Invoice) PayeeHex() string {
+	if i.Payee == nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", i.Payee.SerializeCompressed())
+}
+
+func fromZpay32(paymentRequest string, net network, decoded *zpay32.Invoice) *Invoice {
+	inv := &Invoice{
+		PaymentRequest:     paymentRequest,
+		Network:            net.name,
+		PaymentAddr:        decoded.PaymentAddr,
+		DescriptionHash:    decoded.DescriptionHash,
+		Payee:              decoded.Destination,
+		Timestamp:          decoded.Timestamp,
+		Expiry:             decoded.Expiry(),
+		MinFinalCLTVExpiry: decoded.MinFinalCLTVExpiry(),
+		RouteHints:         decoded.RouteHints,
+		Features:           decoded.Features,
+	}
+	if decoded.MilliSat != nil {
+		inv.Amount = *decoded.MilliSat
+	}
+	if decoded.PaymentHash != nil {
+		inv.PaymentHash = *decoded.PaymentHash
+	}
+	if decoded.Description != nil {
+		inv.Description = *decoded.Description
+	}
+	if decoded.FallbackAddr != nil {
+		inv.FallbackAddr = fallbackAddr(decoded.FallbackAddr, net.params)
+	}
+	return inv
+}
+
+// fallbackAddr renders a fallback address decoded by zpay32 with the address parameters of its
+// network, which differ from the parameters used to decode the invoice on signet.
+func fallbackAddr(addr btcutil.Address, params *chaincfg.Params) string {
+	script, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return addr.String()
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(script, params)
+	if err != nil || len(addrs) != 1 {
+		return addr.String()
+	}
+	return addrs[0].String()
+}
@@ -0,0 +1,127 @@
+package invoice
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// bolt11Example is the "1 cup coffee" example from the BOLT11 specification.
+	bolt11Example = "lnbc2500u1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpuaztrnwngzn3kdzw5hydlzf03qdgm2hdq27cqv3agm2awhz5se903vruatfhq77w3ls4evs3ch9zw97j25emudupq63nyw24cg27h2rspfj9srp"
+
+	// bolt11Payee is the node that signed the examples in the BOLT11 specification.
+	bolt11Payee = "03e7156ae33b0a208d0744199163177e909e80176e55d97a2f221ede0f934dd9ad"
+)
+
+func TestDecodeSpecExample(t *testing.T) {
+	inv, err := Decode(bolt11Example)
+	require.NoError(t, err)
+
+	require.Equal(t, "mainnet", inv.Network)
+	require.Equal(t, lnwire.MilliSatoshi(250_000_000), inv.Amount)
+	require.Equal(t, "0001020304050607080900010203040506070809000102030405060708090102", inv.PaymentHash.String())
+	require.Equal(t, "1 cup coffee", inv.Description)
+	require.Equal(t, time.Minute, inv.Expiry)
+	require.Equal(t, time.Unix(1496314658, 0), inv.Timestamp)
+	require.Equal(t, bolt11Payee, inv.PayeeHex())
+	require.True(t, inv.Expired(time.Now()))
+}
+
+func TestDecodeNetworks(t *testing.T) {
+	for _, net := range networks {
+		t.Run(net.name, func(t *testing.T) {
+			privKey, encoded := newTestInvoice(t, net, zpay32.Amount(1000), zpay32.Description(net.name),
+				zpay32.Expiry(10*time.Minute))
+			require.True(t, strings.HasPrefix(encoded, "ln"+net.hrp))
+
+			decoded, err := Decode(encoded)
+			require.NoError(t, err)
+			paymentHash := sha256.Sum256([]byte(net.name))
+			require.Equal(t, net.name, decoded.Network)
+			require.Equal(t, lnwire.MilliSatoshi(1000), decoded.Amount)
+			require.Equal(t, paymentHash[:], decoded.PaymentHash[:])
+			require.Equal(t, net.name, decoded.Description)
+			require.True(t, decoded.Payee.IsEqual(privKey.PubKey()))
+			require.False(t, decoded.Expired(time.Now()))
+		})
+	}
+}
+
+// TestDecodeFallbackAddr verifies that fallback addresses are rendered for the network of the
+// invoice, including on signet, whose addresses use the "tb" prefix of testnet.
+func TestDecodeFallbackAddr(t *testing.T) {
+	for _, net := range networks {
+		if net.name != "signet" && net.name != "testnet" {
+			continue
+		}
+		t.Run(net.name, func(t *testing.T) {
+			segwit, err := btcutil.NewAddressWitnessPubKeyHash(make([]byte, 20), net.params)
+			require.NoError(t, err)
+			legacy, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), net.params)
+			require.NoError(t, err)
+
+			for _, addr := range []btcutil.Address{segwit, legacy} {
+				_, encoded := newTestInvoice(t, net, zpay32.Description("fallback"), zpay32.FallbackAddr(addr))
+
+				decoded, err := Decode(encoded)
+				require.NoError(t, err)
+				require.Equal(t, addr.String(), decoded.FallbackAddr)
+			}
+			require.True(t, strings.HasPrefix(segwit.String(), "tb1"))
+		})
+	}
+}
+
+// newTestInvoice encodes an invoice for net, with a payment hash derived from the network name,
+// signed by a new key.
+func newTestInvoice(t *testing.T, net network, options ...func(*zpay32.Invoice)) (*btcec.PrivateKey, string) {
+	t.Helper()
+
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	require.NoError(t, err)
+
+	paymentHash := sha256.Sum256([]byte(net.name))
+	inv, err := zpay32.NewInvoice(net.invoiceParams(), paymentHash, time.Now(), options...)
+	require.NoError(t, err)
+
+	encoded, err := inv.Encode(zpay32.MessageSigner{
+		SignCompact: func(hash []byte) ([]byte, error) {
+			return btcec.SignCompact(btcec.S256(), privKey, hash, true)
+		},
+	})
+	require.NoError(t, err)
+	return privKey, encoded
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		invoice string
+	}{
+		{"Empty", ""},
+		{"Unknown prefix", "lnxyz2500u1pvjluez"},
+		{"Bad checksum", bolt11Example[:len(bolt11Example)-1] + "q"},
+		{"Not an invoice", "testInvoice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.invoice)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeUppercaseWithScheme(t *testing.T) {
+	inv, err := Decode("LIGHTNING:" + strings.ToUpper(bolt11Example))
+	require.NoError(t, err)
+	require.Equal(t, "1 cup coffee", inv.Description)
+}
@@ -0,0 +1,222 @@
+package macaroons
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ServicesCondition is the condition of the caveat listing the services a macaroon grants access to.
+	ServicesCondition = "services"
+
+	// PathsCondition is the condition of the caveat restricting a macaroon to URL path prefixes.
+	PathsCondition = "paths"
+
+	// capabilitiesSuffix is appended to a service name to form the condition of its capabilities caveat.
+	capabilitiesSuffix = "_capabilities"
+
+	// validUntilSuffix is appended to a service name to form the condition of its expiry caveat.
+	validUntilSuffix = "_valid_until"
+)
+
+var (
+	// ErrInvalidCaveat is returned when a caveat is not of the form "condition=value".
+	ErrInvalidCaveat = errors.New("invalid caveat")
+
+	// ErrUnsatisfiedCaveat is returned when a caveat is unknown or not satisfied.
+	ErrUnsatisfiedCaveat = errors.New("caveat not satisfied")
+)
+
+// Caveat is a first-party caveat of the form "condition=value".
+type Caveat struct {
+	Condition string
+	Value     string
+}
+
+// NewCaveat creates a caveat with the given condition and value.
+func NewCaveat(condition, value string) Caveat {
+	return Caveat{Condition: condition, Value: value}
+}
+
+// String returns the caveat in its "condition=value" encoding.
+func (c Caveat) String() string {
+	return c.Condition + "=" + c.Value
+}
+
+// ParseCaveat parses a caveat of the form "condition=value".
+func ParseCaveat(s string) (Caveat, error) {
+	condition, value, ok := strings.Cut(s, "=")
+	condition, value = strings.TrimSpace(condition), strings.TrimSpace(value)
+	if !ok || condition == "" || value == "" {
+		return Caveat{}, fmt.Errorf("%w: %q", ErrInvalidCaveat, s)
+	}
+	return Caveat{Condition: condition, Value: value}, nil
+}
+
+// Service is a service a macaroon grants access to, at a pricing tier.
+type Service struct {
+	Name string
+	Tier int
+}
+
+// String returns the service in its "name:tier" encoding.
+func (s Service) String() string {
+	return s.Name + ":" + strconv.Itoa(s.Tier)
+}
+
+// NewServicesCaveat creates a caveat restricting a macaroon to the given services.
+func NewServicesCaveat(services ...Service) (Caveat, error) {
+	if len(services) == 0 {
+		return Caveat{}, fmt.Errorf("%w: no services", ErrInvalidCaveat)
+	}
+
+	encoded := make([]string, 0, len(services))
+	for _, service := range services {
+		if service.Name == "" || strings.ContainsAny(service.Name, ",:=") {
+			return Caveat{}, fmt.Errorf("%w: invalid service name %q", ErrInvalidCaveat, service.Name)
+		}
+		encoded = append(encoded, service.String())
+	}
+	return NewCaveat(ServicesCondition, strings.Join(encoded, ",")), nil
+}
+
+// ParseServices parses the value of a services caveat. A missing tier defaults to 0.
+func ParseServices(value string) ([]Service, error) {
+	var services []Service
+	for _, entry := range strings.Split(value, ",") {
+		name, tier, hasTier := strings.Cut(strings.TrimSpace(entry), ":")
+		if name == "" {
+			return nil, fmt.Errorf("%w: empty service name in %q", ErrInvalidCaveat, value)
+		}
+
+		service := Service{Name: name}
+		if hasTier {
+			t, err := strconv.Atoi(tier)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid tier of service %q: %v", ErrInvalidCaveat, name, err)
+			}
+			service.Tier = t
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// CapabilitiesCondition returns the condition of the capabilities caveat of service.
+func CapabilitiesCondition(service string) string {
+	return service + capabilitiesSuffix
+}
+
+// NewCapabilitiesCaveat creates a caveat restricting access to service to the given capabilities.
+func NewCapabilitiesCaveat(service string, capabilities ...string) Caveat {
+	return NewCaveat(CapabilitiesCondition(service), strings.Join(capabilities, ","))
+}
+
+// ValidUntilCondition returns the condition of the expiry caveat of service.
+func ValidUntilCondition(service string) string {
+	return service + validUntilSuffix
+}
+
+// NewValidUntilCaveat creates a caveat that expires access to service at t.
+func NewValidUntilCaveat(service string, t time.Time) Caveat {
+	return NewCaveat(ValidUntilCondition(service), strconv.FormatInt(t.Unix(), 10))
+}
+
+// NewPathsCaveat creates a caveat restricting a macaroon to URLs under the given path prefixes.
+func NewPathsCaveat(prefixes ...string) Caveat {
+	return NewCaveat(PathsCondition, strings.Join(prefixes, ","))
+}
+
+// MatchPath reports whether path is prefix or lies below it. Prefixes match whole
+// path segments, so "/api" matches "/api" and "/api/v1" but not "/apis".
+func MatchPath(prefix, path string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// Satisfier checks the caveats with a given condition. Verification fails if a caveat
+// has no satisfier, so every condition a service issues needs one.
+type Satisfier struct {
+	// Condition is the condition of the caveats this satisfier checks.
+	Condition string
+
+	// Satisfy is called with the value of every caveat with the condition. Caveats can only be
+	// added to a macaroon, never removed, so each one has to hold for the request to be allowed.
+	Satisfy func(value string) error
+}
+
+// ServicesSatisfier requires services caveats to include service.
+func ServicesSatisfier(service string) Satisfier {
+	return Satisfier{
+		Condition: ServicesCondition,
+		Satisfy: func(value string) error {
+			services, err := ParseServices(value)
+			if err != nil {
+				return err
+			}
+			for _, s := range services {
+				if s.Name == service {
+					return nil
+				}
+			}
+			return fmt.Errorf("macaroon not valid for service %q", service)
+		},
+	}
+}
+
+// CapabilitiesSatisfier requires capabilities caveats of service to include capability.
+func CapabilitiesSatisfier(service, capability string) Satisfier {
+	return Satisfier{
+		Condition: CapabilitiesCondition(service),
+		Satisfy: func(value string) error {
+			for _, c := range strings.Split(value, ",") {
+				if strings.TrimSpace(c) == capability {
+					return nil
+				}
+			}
+			return fmt.Errorf("macaroon does not grant capability %q of service %q", capability, service)
+		},
+	}
+}
+
+// PathsSatisfier requires paths caveats to include a prefix of path.
+func PathsSatisfier(path string) Satisfier {
+	return Satisfier{
+		Condition: PathsCondition,
+		Satisfy: func(value string) error {
+			for _, prefix := range strings.Split(value, ",") {
+				if MatchPath(strings.TrimSpace(prefix), path) {
+					return nil
+				}
+			}
+			return fmt.Errorf("macaroon not valid for path %q", path)
+		},
+	}
+}
+
+// ValidUntilSatisfier requires expiry caveats of service to be in the future.
+// If now is nil, time.Now is used.
+func ValidUntilSatisfier(service string, now func() time.Time) Satisfier {
+	if now == nil {
+		now = time.Now
+	}
+	return Satisfier{
+		Condition: ValidUntilCondition(service),
+		Satisfy: func(value string) error {
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: invalid expiry %q", ErrInvalidCaveat, value)
+			}
+			if expiry := time.Unix(unix, 0); !now().Before(expiry) {
+				return fmt.Errorf("macaroon for service %q expired at %v", service, expiry)
+			}
+			return nil
+		},
+	}
+}
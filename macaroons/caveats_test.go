@@ -0,0 +1,91 @@
+package macaroons
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCaveat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Caveat
+		wantErr bool
+	}{
+		{"services=svc:0", Caveat{"services", "svc:0"}, false},
+		{"svc_valid_until = 1700000000", Caveat{"svc_valid_until", "1700000000"}, false},
+		{"a=b=c", Caveat{"a", "b=c"}, false},
+		{"no condition", Caveat{}, true},
+		{"=value", Caveat{}, true},
+		{"condition=", Caveat{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseCaveat(tt.input)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidCaveat)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServicesCaveat(t *testing.T) {
+	caveat, err := NewServicesCaveat(Service{Name: "a", Tier: 0}, Service{Name: "b", Tier: 2})
+	require.NoError(t, err)
+	require.Equal(t, "services=a:0,b:2", caveat.String())
+
+	services, err := ParseServices(caveat.Value)
+	require.NoError(t, err)
+	require.Equal(t, []Service{{"a", 0}, {"b", 2}}, services)
+
+	services, err = ParseServices("a, b:1")
+	require.NoError(t, err)
+	require.Equal(t, []Service{{"a", 0}, {"b", 1}}, services)
+
+	_, err = ParseServices("a:x")
+	require.ErrorIs(t, err, ErrInvalidCaveat)
+	_, err = NewServicesCaveat()
+	require.ErrorIs(t, err, ErrInvalidCaveat)
+	_, err = NewServicesCaveat(Service{Name: "a,b"})
+	require.ErrorIs(t, err, ErrInvalidCaveat)
+}
+
+func TestSatisfiers(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+
+	tests := []struct {
+		name      string
+		satisfier Satisfier
+		caveat    Caveat
+		wantErr   bool
+	}{
+		{"Service listed", ServicesSatisfier("b"), NewCaveat("services", "a:0,b:1"), false},
+		{"Service not listed", ServicesSatisfier("c"), NewCaveat("services", "a:0,b:1"), true},
+		{"Capability granted", CapabilitiesSatisfier("svc", "read"), NewCapabilitiesCaveat("svc", "read", "write"), false},
+		{"Capability not granted", CapabilitiesSatisfier("svc", "admin"), NewCapabilitiesCaveat("svc", "read"), true},
+		{"Path prefix", PathsSatisfier("/api/v1"), NewPathsCaveat("/static", "/api"), false},
+		{"Path segment mismatch", PathsSatisfier("/apis"), NewPathsCaveat("/api"), true},
+		{"Not expired", ValidUntilSatisfier("svc", clock), NewValidUntilCaveat("svc", now.Add(time.Minute)), false},
+		{"Expired", ValidUntilSatisfier("svc", clock), NewValidUntilCaveat("svc", now), true},
+		{"Malformed expiry", ValidUntilSatisfier("svc", clock), NewCaveat("svc_valid_until", "soon"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.caveat.Condition, tt.satisfier.Condition)
+
+			err := tt.satisfier.Satisfy(tt.caveat.Value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package macaroons
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+)
+
+const (
+	// LatestVersion is the latest version of the L402 macaroon identifier.
+	LatestVersion = 0
+
+	// TokenIDSize is the size of the token ID in the macaroon identifier.
+	TokenIDSize = 32
+
+	// identifierLength is the length of a version 0 identifier:
+	// a 2 byte version, a 32 byte payment hash and a 32 byte token ID.
+	identifierLength = 2 + lntypes.HashSize + TokenIDSize
+)
+
+var (
+	// ErrUnknownVersion is returned when decoding an identifier with an unsupported version.
+	ErrUnknownVersion = errors.New("unknown L402 identifier version")
+
+	// ErrInvalidIdentifier is returned when an identifier does not have the expected length.
+	ErrInvalidIdentifier = errors.New("invalid L402 identifier")
+)
+
+// TokenID uniquely identifies an L402 token.
+type TokenID [TokenIDSize]byte
+
+// NewTokenID generates a random token ID.
+func NewTokenID() (TokenID, error) {
+	var id TokenID
+	if _, err := rand.Read(id[:]); err != nil {
+		return id, fmt.Errorf("unable to generate token ID: %w", err)
+	}
+	return id, nil
+}
+
+// String returns the hex encoding of the token ID.
+func (id TokenID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Identifier is the identifier of an L402 macaroon. It commits to the payment hash of the
+// invoice that has to be paid for the macaroon to be usable.
+type Identifier struct {
+	// Version is the version of the identifier.
+	Version uint16
+
+	// PaymentHash is the payment hash of the invoice paid for the token.
+	PaymentHash lntypes.Hash
+
+	// TokenID is the unique ID of the token. It also selects the root key the macaroon is signed with.
+	TokenID TokenID
+}
+
+// Encode serializes the identifier.
+func (id Identifier) Encode() ([]byte, error) {
+	if id.Version != LatestVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, id.Version)
+	}
+
+	raw := make([]byte, identifierLength)
+	binary.BigEndian.PutUint16(raw[:2], id.Version)
+	copy(raw[2:], id.PaymentHash[:])
+	copy(raw[2+lntypes.HashSize:], id.TokenID[:])
+	return raw, nil
+}
+
+// DecodeIdentifier deserializes an identifier created by Encode.
+func DecodeIdentifier(raw []byte) (*Identifier, error) {
+	if len(raw) < 2 {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidIdentifier)
+	}
+
+	version := binary.BigEndian.Uint16(raw[:2])
+	if version != LatestVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	if len(raw) != identifierLength {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidIdentifier, identifierLength, len(raw))
+	}
+
+	id := &Identifier{Version: version}
+	copy(id.PaymentHash[:], raw[2:2+lntypes.HashSize])
+	copy(id.TokenID[:], raw[2+lntypes.HashSize:])
+	return id, nil
+}
@@ -0,0 +1,52 @@
+package macaroons
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentifierRoundTrip(t *testing.T) {
+	tokenID, err := NewTokenID()
+	require.NoError(t, err)
+
+	id := Identifier{
+		Version:     LatestVersion,
+		PaymentHash: lntypes.Hash{1, 2, 3},
+		TokenID:     tokenID,
+	}
+	raw, err := id.Encode()
+	require.NoError(t, err)
+	require.Len(t, raw, identifierLength)
+
+	decoded, err := DecodeIdentifier(raw)
+	require.NoError(t, err)
+	require.Equal(t, id, *decoded)
+}
+
+func TestDecodeIdentifierErrors(t *testing.T) {
+	valid, err := Identifier{}.Encode()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		raw     []byte
+		wantErr error
+	}{
+		{"Empty", nil, ErrInvalidIdentifier},
+		{"Unknown version", append([]byte{0, 1}, valid[2:]...), ErrUnknownVersion},
+		{"Truncated", valid[:len(valid)-1], ErrInvalidIdentifier},
+		{"Trailing data", append(valid, 0), ErrInvalidIdentifier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeIdentifier(tt.raw)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	_, err = Identifier{Version: 1}.Encode()
+	require.ErrorIs(t, err, ErrUnknownVersion)
+}
@@ -0,0 +1,174 @@
+// Package macaroons mints, decodes and verifies L402 macaroons.
+//
+// An L402 macaroon has an identifier committing to the payment hash of an invoice and to a
+// random token ID, which selects the root key it is signed with. Access is restricted with
+// first-party caveats of the form "condition=value", such as "services=name:tier".
+package macaroons
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"gopkg.in/macaroon.v2"
+)
+
+// ErrInvalidMacaroon is returned when a macaroon cannot be decoded.
+var ErrInvalidMacaroon = errors.New("invalid macaroon")
+
+// Mint creates a macaroon committing to paymentHash, signed with a new root key from store
+// and restricted by the given caveats.
+func Mint(ctx context.Context, store RootKeyStore, paymentHash lntypes.Hash, location string, caveats ...Caveat) (*macaroon.Macaroon, error) {
+	tokenID, err := NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := Identifier{
+		Version:     LatestVersion,
+		PaymentHash: paymentHash,
+		TokenID:     tokenID,
+	}.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	rootKey, err := store.NewRootKey(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := macaroon.New(rootKey, id, location, macaroon.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := AddCaveats(mac, caveats...); err != nil {
+		return nil, err
+	}
+	return mac, nil
+}
+
+// AddCaveats adds first-party caveats to mac. Anyone holding a macaroon can restrict it further this way.
+func AddCaveats(mac *macaroon.Macaroon, caveats ...Caveat) error {
+	for _, caveat := range caveats {
+		if err := mac.AddFirstPartyCaveat([]byte(caveat.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Encode returns the base64 encoding of mac used in L402 challenges and tokens.
+func Encode(mac *macaroon.Macaroon) (string, error) {
+	raw, err := mac.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// Decode decodes a binary macaroon encoded as base64, as in L402 headers, or as hex,
+// as printed by lnd and Aperture tooling.
+func Decode(encoded string) (*macaroon.Macaroon, error) {
+	// A hex string of even length is often also valid base64, so fall back to hex when the
+	// base64 decoding does not unmarshal either.
+	var firstErr error
+	for _, decode := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		raw, err := decode(encoded)
+		if err == nil {
+			var mac macaroon.Macaroon
+			if err = mac.UnmarshalBinary(raw); err == nil {
+				return &mac, nil
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", ErrInvalidMacaroon, firstErr)
+}
+
+// Caveats returns the first-party caveats of mac in the order they were added.
+func Caveats(mac *macaroon.Macaroon) ([]Caveat, error) {
+	var caveats []Caveat
+	for _, c := range mac.Caveats() {
+		if c.VerificationId != nil {
+			continue
+		}
+		caveat, err := ParseCaveat(string(c.Id))
+		if err != nil {
+			return nil, err
+		}
+		caveats = append(caveats, caveat)
+	}
+	return caveats, nil
+}
+
+// ValidUntil returns the earliest expiry among the valid_until caveats of mac, for any service.
+// It returns false if mac carries no such caveat.
+func ValidUntil(mac *macaroon.Macaroon) (time.Time, bool) {
+	var earliest time.Time
+	for _, c := range mac.Caveats() {
+		caveat, err := ParseCaveat(string(c.Id))
+		if err != nil || !strings.HasSuffix(caveat.Condition, validUntilSuffix) {
+			continue
+		}
+		unix, err := strconv.ParseInt(caveat.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if expiry := time.Unix(unix, 0); earliest.IsZero() || expiry.Before(earliest) {
+			earliest = expiry
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// Verify checks the signature of mac with the root key of its token and checks every
+// first-party caveat with the satisfier for its condition. It returns the decoded identifier,
+// so the caller can check the payment preimage against it.
+func Verify(ctx context.Context, store RootKeyStore, mac *macaroon.Macaroon, satisfiers ...Satisfier) (*Identifier, error) {
+	id, err := DecodeIdentifier(mac.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	rootKey, err := store.RootKey(ctx, id.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	byCondition := make(map[string]Satisfier, len(satisfiers))
+	for _, s := range satisfiers {
+		byCondition[s.Condition] = s
+	}
+
+	check := func(raw string) error {
+		caveat, err := ParseCaveat(raw)
+		if err != nil {
+			return err
+		}
+		satisfier, ok := byCondition[caveat.Condition]
+		if !ok {
+			return fmt.Errorf("%w: unknown condition %q", ErrUnsatisfiedCaveat, caveat.Condition)
+		}
+		if err := satisfier.Satisfy(caveat.Value); err != nil {
+			return fmt.Errorf("%w: %v", ErrUnsatisfiedCaveat, err)
+		}
+		return nil
+	}
+
+	if err := mac.Verify(rootKey, check, nil); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
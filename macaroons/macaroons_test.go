@@ -0,0 +1,168 @@
+package macaroons
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/stretchr/testify/require"
+)
+
+var testRootKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestMintAndVerify(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStaticRootKeyStore(testRootKey)
+	require.NoError(t, err)
+
+	paymentHash := lntypes.Hash{1}
+	services, err := NewServicesCaveat(Service{Name: "svc"})
+	require.NoError(t, err)
+
+	mac, err := Mint(ctx, store, paymentHash, "example.com", services)
+	require.NoError(t, err)
+	require.Equal(t, "example.com", mac.Location())
+
+	encoded, err := Encode(mac)
+	require.NoError(t, err)
+	decoded, err := Decode(encoded)
+	require.NoError(t, err)
+
+	caveats, err := Caveats(decoded)
+	require.NoError(t, err)
+	require.Equal(t, []Caveat{services}, caveats)
+
+	id, err := Verify(ctx, store, decoded, ServicesSatisfier("svc"))
+	require.NoError(t, err)
+	require.Equal(t, paymentHash, id.PaymentHash)
+
+	// Lnd and Aperture tooling print macaroons as hex.
+	raw, err := mac.MarshalBinary()
+	require.NoError(t, err)
+	fromHex, err := Decode(hex.EncodeToString(raw))
+	require.NoError(t, err)
+	require.Equal(t, mac.Signature(), fromHex.Signature())
+
+	_, err = Decode("not a macaroon")
+	require.ErrorIs(t, err, ErrInvalidMacaroon)
+}
+
+func TestDecodeHex(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStaticRootKeyStore(testRootKey)
+	require.NoError(t, err)
+
+	// The hex encoding of some macaroons is also valid base64, depending on their length.
+	for n := 0; n < 8; n++ {
+		var caveats []Caveat
+		for i := 0; i < n; i++ {
+			caveats = append(caveats, NewCaveat(fmt.Sprintf("svc%d_capabilities", i), "read"))
+		}
+		mac, err := Mint(ctx, store, lntypes.Hash{byte(n)}, "example.com", caveats...)
+		require.NoError(t, err)
+		raw, err := mac.MarshalBinary()
+		require.NoError(t, err)
+
+		for _, encoded := range []string{hex.EncodeToString(raw), strings.ToUpper(hex.EncodeToString(raw))} {
+			decoded, err := Decode(encoded)
+			require.NoError(t, err, "%d caveats", n)
+			require.Equal(t, mac.Signature(), decoded.Signature(), "%d caveats", n)
+		}
+	}
+}
+
+func TestVerifyFailures(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemRootKeyStore()
+	services, err := NewServicesCaveat(Service{Name: "svc"})
+	require.NoError(t, err)
+
+	mint := func(t *testing.T, caveats ...Caveat) *Identifier {
+		t.Helper()
+		mac, err := Mint(ctx, store, lntypes.Hash{}, "", caveats...)
+		require.NoError(t, err)
+		id, err := DecodeIdentifier(mac.Id())
+		require.NoError(t, err)
+		_, err = Verify(ctx, store, mac, ServicesSatisfier("svc"))
+		require.NoError(t, err)
+		return id
+	}
+
+	t.Run("Attenuated", func(t *testing.T) {
+		mac, err := Mint(ctx, store, lntypes.Hash{}, "", services)
+		require.NoError(t, err)
+		require.NoError(t, AddCaveats(mac, NewValidUntilCaveat("svc", time.Now().Add(-time.Minute))))
+
+		_, err = Verify(ctx, store, mac, ServicesSatisfier("svc"), ValidUntilSatisfier("svc", nil))
+		require.ErrorIs(t, err, ErrUnsatisfiedCaveat)
+	})
+
+	t.Run("Unknown condition", func(t *testing.T) {
+		mac, err := Mint(ctx, store, lntypes.Hash{}, "", services, NewCaveat("ip", "127.0.0.1"))
+		require.NoError(t, err)
+
+		_, err = Verify(ctx, store, mac, ServicesSatisfier("svc"))
+		require.ErrorIs(t, err, ErrUnsatisfiedCaveat)
+	})
+
+	t.Run("Wrong service", func(t *testing.T) {
+		mac, err := Mint(ctx, store, lntypes.Hash{}, "", services)
+		require.NoError(t, err)
+
+		_, err = Verify(ctx, store, mac, ServicesSatisfier("other"))
+		require.ErrorIs(t, err, ErrUnsatisfiedCaveat)
+	})
+
+	t.Run("Forged", func(t *testing.T) {
+		mac, err := Mint(ctx, store, lntypes.Hash{}, "", services)
+		require.NoError(t, err)
+
+		otherStore, err := NewStaticRootKeyStore([]byte("fedcba9876543210fedcba9876543210"))
+		require.NoError(t, err)
+		_, err = Verify(ctx, otherStore, mac, ServicesSatisfier("svc"))
+		require.Error(t, err)
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		mac, err := Mint(ctx, store, lntypes.Hash{}, "", services)
+		require.NoError(t, err)
+		id, err := DecodeIdentifier(mac.Id())
+		require.NoError(t, err)
+
+		store.Delete(id.TokenID)
+		_, err = Verify(ctx, store, mac, ServicesSatisfier("svc"))
+		require.ErrorIs(t, err, ErrRootKeyNotFound)
+	})
+
+	t.Run("Distinct tokens", func(t *testing.T) {
+		require.NotEqual(t, mint(t, services).TokenID, mint(t, services).TokenID)
+	})
+}
+
+func TestStaticRootKeyStore(t *testing.T) {
+	_, err := NewStaticRootKeyStore([]byte("short"))
+	require.Error(t, err)
+}
+
+func TestValidUntil(t *testing.T) {
+	store, err := NewStaticRootKeyStore(testRootKey)
+	require.NoError(t, err)
+
+	mac, err := Mint(context.Background(), store, lntypes.Hash{}, "")
+	require.NoError(t, err)
+	_, ok := ValidUntil(mac)
+	require.False(t, ok)
+
+	earliest := time.Unix(1700000000, 0)
+	require.NoError(t, AddCaveats(mac,
+		NewValidUntilCaveat("a", earliest.Add(time.Hour)),
+		NewValidUntilCaveat("b", earliest),
+	))
+	expiry, ok := ValidUntil(mac)
+	require.True(t, ok)
+	require.Equal(t, earliest, expiry)
+}
@@ -0,0 +1,99 @@
+package macaroons
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RootKeySize is the size of the root keys generated by MemRootKeyStore.
+const RootKeySize = 32
+
+// ErrRootKeyNotFound is returned when no root key is known for a token ID.
+var ErrRootKeyNotFound = errors.New("root key not found")
+
+// RootKeyStore provides the secret root keys macaroons are signed with.
+type RootKeyStore interface {
+	// NewRootKey returns the root key to sign the macaroon of a new token.
+	NewRootKey(ctx context.Context, id TokenID) ([]byte, error)
+
+	// RootKey returns the root key the macaroon of the token was signed with.
+	RootKey(ctx context.Context, id TokenID) ([]byte, error)
+}
+
+// StaticRootKeyStore signs all macaroons with a single root key.
+type StaticRootKeyStore struct {
+	key []byte
+}
+
+// NewStaticRootKeyStore creates a root key store that always returns key.
+// The key must be kept private and should be at least 32 random bytes.
+func NewStaticRootKeyStore(key []byte) (*StaticRootKeyStore, error) {
+	if len(key) < RootKeySize {
+		return nil, fmt.Errorf("root key must be at least %d bytes", RootKeySize)
+	}
+	return &StaticRootKeyStore{key: key}, nil
+}
+
+// NewRootKey returns the static root key.
+func (s *StaticRootKeyStore) NewRootKey(ctx context.Context, id TokenID) ([]byte, error) {
+	return s.key, nil
+}
+
+// RootKey returns the static root key.
+func (s *StaticRootKeyStore) RootKey(ctx context.Context, id TokenID) ([]byte, error) {
+	return s.key, nil
+}
+
+// MemRootKeyStore generates a random root key per token and keeps it in memory.
+// Deleting a token's root key revokes its macaroon.
+type MemRootKeyStore struct {
+	mu   sync.RWMutex
+	keys map[TokenID][]byte
+}
+
+// NewMemRootKeyStore creates a new instance of MemRootKeyStore.
+func NewMemRootKeyStore() *MemRootKeyStore {
+	return &MemRootKeyStore{
+		keys: make(map[TokenID][]byte),
+	}
+}
+
+// NewRootKey generates and stores a random root key for the token.
+func (s *MemRootKeyStore) NewRootKey(ctx context.Context, id TokenID) ([]byte, error) {
+	key := make([]byte, RootKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("unable to generate root key: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[id]; exists {
+		return nil, fmt.Errorf("root key for token %v already exists", id)
+	}
+	s.keys[id] = key
+	return key, nil
+}
+
+// RootKey returns the root key of the token, or ErrRootKeyNotFound.
+func (s *MemRootKeyStore) RootKey(ctx context.Context, id TokenID) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrRootKeyNotFound, id)
+	}
+	return key, nil
+}
+
+// Delete removes the root key of the token, revoking its macaroon.
+func (s *MemRootKeyStore) Delete(id TokenID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, id)
+}
@@ -0,0 +1,167 @@
+// Package server provides net/http middleware that protects handlers with L402 payments.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/macaroons"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+var (
+	// ErrInvalidToken is returned when the L402 token in the Authorization header is malformed
+	// or was not minted by this middleware.
+	ErrInvalidToken = errors.New("invalid L402 token")
+
+	// ErrInvalidPreimage is returned when the preimage in the Authorization header does not match
+	// the payment hash committed to by the macaroon.
+	ErrInvalidPreimage = errors.New("invalid L402 preimage")
+)
+
+// Config holds the configuration of the L402 middleware.
+type Config struct {
+	// Invoicer creates the invoices for challenges.
+	Invoicer wallet.Invoicer
+
+	// RootKey is the secret used to sign and verify macaroons. It must be kept private
+	// and should be at least 32 random bytes. It is ignored if RootKeyStore is set.
+	RootKey []byte
+
+	// RootKeyStore, if set, provides a root key per token instead of the single RootKey.
+	RootKeyStore macaroons.RootKeyStore
+
+	// Service is the name of the service macaroons grant access to.
+	Service string
+
+	// Price is the amount charged for a token.
+	Price lnwire.MilliSatoshi
+
+	// Location is the location recorded in minted macaroons. Optional.
+	Location string
+
+	// Memo is the description of the invoices. Defaults to "L402 access to <Service>".
+	Memo string
+
+	// InvoiceExpiry is how long challenge invoices remain payable. Zero uses the backend default.
+	InvoiceExpiry time.Duration
+}
+
+// Middleware issues L402 challenges for unauthenticated requests and validates L402 tokens.
+type Middleware struct {
+	cfg      Config
+	rootKeys macaroons.RootKeyStore
+}
+
+// New creates a new L402 middleware from the given configuration.
+func New(cfg Config) (*Middleware, error) {
+	if cfg.Invoicer == nil {
+		return nil, fmt.Errorf("invoicer is required")
+	}
+	if cfg.Service == "" {
+		return nil, fmt.Errorf("service name is required")
+	}
+	if cfg.Memo == "" {
+		cfg.Memo = "L402 access to " + cfg.Service
+	}
+
+	rootKeys := cfg.RootKeyStore
+	if rootKeys == nil {
+		static, err := macaroons.NewStaticRootKeyStore(cfg.RootKey)
+		if err != nil {
+			return nil, err
+		}
+		rootKeys = static
+	}
+
+	return &Middleware{cfg: cfg, rootKeys: rootKeys}, nil
+}
+
+// Handler wraps next so that it is only reached by requests carrying a valid L402 token.
+// Other requests receive a 402 Payment Required response with a fresh challenge.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := m.Validate(r.Context(), r.Header.Get("Authorization")); err == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.challenge(w, r)
+	})
+}
+
+// Validate checks an Authorization header of the form "L402 <macaroon>:<preimage>".
+// The legacy LSAT scheme is accepted as well.
+func (m *Middleware) Validate(ctx context.Context, authHeader string) error {
+	scheme, credentials, ok := strings.Cut(authHeader, " ")
+	if !ok || !(strings.EqualFold(scheme, "L402") || strings.EqualFold(scheme, "LSAT")) {
+		return fmt.Errorf("%w: missing L402 authorization", ErrInvalidToken)
+	}
+
+	encodedMac, encodedPreimage, ok := strings.Cut(strings.TrimSpace(credentials), ":")
+	if !ok {
+		return fmt.Errorf("%w: expected <macaroon>:<preimage>", ErrInvalidToken)
+	}
+
+	mac, err := macaroons.Decode(encodedMac)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	id, err := macaroons.Verify(ctx, m.rootKeys, mac, macaroons.ServicesSatisfier(m.cfg.Service))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	preimage, err := lntypes.MakePreimageFromStr(encodedPreimage)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPreimage, err)
+	}
+	if !preimage.Matches(id.PaymentHash) {
+		return ErrInvalidPreimage
+	}
+
+	return nil
+}
+
+// challenge creates an invoice and a macaroon bound to its payment hash, and responds with 402 Payment Required.
+func (m *Middleware) challenge(w http.ResponseWriter, r *http.Request) {
+	invoice, err := m.cfg.Invoicer.CreateInvoice(r.Context(), wallet.InvoiceRequest{
+		Amount: m.cfg.Price,
+		Memo:   m.cfg.Memo,
+		Expiry: m.cfg.InvoiceExpiry,
+	})
+	if err != nil {
+		http.Error(w, "failed to create invoice", http.StatusInternalServerError)
+		return
+	}
+
+	mac, err := m.mint(r.Context(), invoice.PaymentHash)
+	if err != nil {
+		http.Error(w, "failed to create macaroon", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`L402 macaroon="%s", invoice="%s"`, mac, invoice.PaymentRequest))
+	http.Error(w, "payment required", http.StatusPaymentRequired)
+}
+
+// mint creates a base64 encoded macaroon committing to paymentHash and restricted to the configured service.
+func (m *Middleware) mint(ctx context.Context, paymentHash lntypes.Hash) (string, error) {
+	services, err := macaroons.NewServicesCaveat(macaroons.Service{Name: m.cfg.Service})
+	if err != nil {
+		return "", err
+	}
+
+	mac, err := macaroons.Mint(ctx, m.rootKeys, paymentHash, m.cfg.Location, services)
+	if err != nil {
+		return "", err
+	}
+	return macaroons.Encode(mac)
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/client"
+	"github.com/sulusolutions/gol402/macaroons"
+	"github.com/sulusolutions/gol402/tokenstore"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+var testRootKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestMiddleware(t *testing.T, invoicer wallet.Invoicer, service string) *Middleware {
+	t.Helper()
+
+	m, err := New(Config{
+		Invoicer: invoicer,
+		RootKey:  testRootKey,
+		Service:  service,
+		Price:    1000,
+	})
+	require.NoError(t, err)
+	return m
+}
+
+// TestMiddlewareWithClient verifies that the L402 client can pay for and access a protected handler.
+func TestMiddlewareWithClient(t *testing.T) {
+	invoicer := wallet.NewMockInvoicer(nil)
+	m := newTestMiddleware(t, invoicer, "randomnumber")
+
+	server := httptest.NewServer(m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("42")) //nolint:errcheck
+	})))
+	defer server.Close()
+
+	c := client.New(wallet.NewMockWallet(nil), tokenstore.NewInMemoryStore())
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// The token bought by the first request is reused by the second.
+	require.Equal(t, 1, invoicer.Created())
+}
+
+func TestMiddlewareChallenge(t *testing.T) {
+	m := newTestMiddleware(t, wallet.NewMockInvoicer(nil), "randomnumber")
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Protected handler reached without payment")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	require.Equal(t, http.StatusPaymentRequired, rec.Code)
+	challenges, err := client.ParseChallenges(rec.Header().Values("WWW-Authenticate"))
+	require.NoError(t, err)
+	require.Len(t, challenges, 1)
+	require.Equal(t, "L402", challenges[0].HeaderKey)
+	require.NotEmpty(t, challenges[0].Macaroon)
+	require.NotEmpty(t, challenges[0].Invoice)
+}
+
+func TestMiddlewareInvoiceError(t *testing.T) {
+	m := newTestMiddleware(t, wallet.NewMockInvoicer(errors.New("node offline")), "randomnumber")
+
+	rec := httptest.NewRecorder()
+	m.Handler(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestMiddlewareValidate(t *testing.T) {
+	m := newTestMiddleware(t, wallet.NewMockInvoicer(nil), "randomnumber")
+	preimage, err := lntypes.MakePreimageFromStr(wallet.MockPreimage)
+	require.NoError(t, err)
+
+	mac, err := m.mint(context.Background(), preimage.Hash())
+	require.NoError(t, err)
+
+	otherService, err := newTestMiddleware(t, wallet.NewMockInvoicer(nil), "other").mint(context.Background(), preimage.Hash())
+	require.NoError(t, err)
+
+	otherKey, err := New(Config{
+		Invoicer: wallet.NewMockInvoicer(nil),
+		RootKey:  []byte("fedcba9876543210fedcba9876543210"),
+		Service:  "randomnumber",
+	})
+	require.NoError(t, err)
+	forged, err := otherKey.mint(context.Background(), preimage.Hash())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		header    string
+		wantError error
+	}{
+		{"Valid L402 token", "L402 " + mac + ":" + wallet.MockPreimage, nil},
+		{"Valid LSAT token", "LSAT " + mac + ":" + wallet.MockPreimage, nil},
+		{"Missing header", "", ErrInvalidToken},
+		{"Other scheme", "Bearer abc", ErrInvalidToken},
+		{"Missing preimage", "L402 " + mac, ErrInvalidToken},
+		{"Garbage macaroon", "L402 garbage:" + wallet.MockPreimage, ErrInvalidToken},
+		{"Other service", "L402 " + otherService + ":" + wallet.MockPreimage, ErrInvalidToken},
+		{"Other root key", "L402 " + forged + ":" + wallet.MockPreimage, ErrInvalidToken},
+		{"Wrong preimage", "L402 " + mac + ":" + lntypes.Preimage{}.String(), ErrInvalidPreimage},
+		{"Malformed preimage", "L402 " + mac + ":1234", ErrInvalidPreimage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Validate(context.Background(), tt.header)
+			if tt.wantError == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.wantError)
+			}
+		})
+	}
+}
+
+// TestMiddlewareRootKeyStore verifies that tokens are revoked with their root key.
+func TestMiddlewareRootKeyStore(t *testing.T) {
+	ctx := context.Background()
+	rootKeys := macaroons.NewMemRootKeyStore()
+	m, err := New(Config{
+		Invoicer:     wallet.NewMockInvoicer(nil),
+		RootKeyStore: rootKeys,
+		Service:      "randomnumber",
+	})
+	require.NoError(t, err)
+
+	preimage, err := lntypes.MakePreimageFromStr(wallet.MockPreimage)
+	require.NoError(t, err)
+	encoded, err := m.mint(ctx, preimage.Hash())
+	require.NoError(t, err)
+	header := "L402 " + encoded + ":" + wallet.MockPreimage
+	require.NoError(t, m.Validate(ctx, header))
+
+	mac, err := macaroons.Decode(encoded)
+	require.NoError(t, err)
+	id, err := macaroons.DecodeIdentifier(mac.Id())
+	require.NoError(t, err)
+	rootKeys.Delete(id.TokenID)
+
+	require.ErrorIs(t, m.Validate(ctx, header), ErrInvalidToken)
+}
+
+func TestNewConfigValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"Missing invoicer", Config{RootKey: testRootKey, Service: "svc"}},
+		{"Short root key", Config{Invoicer: wallet.NewMockInvoicer(nil), RootKey: []byte("short"), Service: "svc"}},
+		{"Missing service", Config{Invoicer: wallet.NewMockInvoicer(nil), RootKey: testRootKey}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// ErrNotSupported is returned by ContextStore methods the underlying store cannot provide.
+var ErrNotSupported = errors.New("operation not supported by token store")
+
+// ContextStore is the context-aware successor of Store. Its methods take a context so networked
+// backends can honour cancellation and deadlines, report errors from lookups, and support
+// enumerating and bulk-deleting tokens for admin tooling.
+type ContextStore interface {
+	// Get looks for a token that matches the given URL, like Store.Get.
+	Get(ctx context.Context, u *url.URL) (Token, bool, error)
+
+	// Put saves a token against the host and path of the URL.
+	Put(ctx context.Context, u *url.URL, token Token) error
+
+	// Delete removes the token stored for the host and path of the URL.
+	Delete(ctx context.Context, u *url.URL) error
+
+	// List returns all stored tokens, including expired ones, ordered by host and path.
+	List(ctx context.Context) ([]Record, error)
+
+	// DeleteHost removes all tokens stored for host.
+	DeleteHost(ctx context.Context, host string) error
+
+	// Clear removes all tokens.
+	Clear(ctx context.Context) error
+}
+
+// Enumerable is implemented by stores whose tokens can be listed and deleted in bulk.
+// All stores in this package implement it.
+type Enumerable interface {
+	List(ctx context.Context) ([]Record, error)
+	DeleteHost(ctx context.Context, host string) error
+	Clear(ctx context.Context) error
+}
+
+// Matcher is implemented by stores that report where the token returned for a URL is stored.
+// A token found by prefix or caveats is stored for another path than the URL, so it has to be
+// deleted by that path. All stores in this package and the ContextStore adapter implement it.
+type Matcher interface {
+	// Match looks for a token that matches the given URL, like Get, and returns it with the
+	// host and path it is stored for.
+	Match(ctx context.Context, u *url.URL) (Record, bool, error)
+}
+
+// NewContextStore adapts a Store to the ContextStore interface. List, DeleteHost and Clear
+// are delegated if the store is Enumerable and fail with ErrNotSupported otherwise.
+// Lookup errors are reported for stores that provide them, such as EncryptedStore.
+func NewContextStore(s Store) ContextStore {
+	return &storeAdapter{store: s}
+}
+
+type storeAdapter struct {
+	store Store
+}
+
+// lookuper is implemented by stores that can report why a lookup failed.
+type lookuper interface {
+	Lookup(u *url.URL) (Token, bool, error)
+}
+
+func (a *storeAdapter) Get(ctx context.Context, u *url.URL) (Token, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Token{}, false, err
+	}
+	if l, ok := a.store.(lookuper); ok {
+		return l.Lookup(u)
+	}
+	token, ok := a.store.Get(u)
+	return token, ok, nil
+}
+
+// Match delegates to the adapted store if it is a Matcher. Otherwise the token is assumed to be
+// stored for the URL itself.
+func (a *storeAdapter) Match(ctx context.Context, u *url.URL) (Record, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Record{}, false, err
+	}
+	if m, ok := a.store.(Matcher); ok {
+		return m.Match(ctx, u)
+	}
+	token, ok, err := a.Get(ctx, u)
+	if err != nil || !ok {
+		return Record{}, false, err
+	}
+	return Record{Host: u.Host, Path: u.Path, Token: token}, true, nil
+}
+
+func (a *storeAdapter) Put(ctx context.Context, u *url.URL, token Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.store.Put(u, token)
+}
+
+func (a *storeAdapter) Delete(ctx context.Context, u *url.URL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.store.Delete(u)
+}
+
+func (a *storeAdapter) List(ctx context.Context) ([]Record, error) {
+	e, ok := a.store.(Enumerable)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return e.List(ctx)
+}
+
+func (a *storeAdapter) DeleteHost(ctx context.Context, host string) error {
+	e, ok := a.store.(Enumerable)
+	if !ok {
+		return ErrNotSupported
+	}
+	return e.DeleteHost(ctx, host)
+}
+
+func (a *storeAdapter) Clear(ctx context.Context) error {
+	e, ok := a.store.(Enumerable)
+	if !ok {
+		return ErrNotSupported
+	}
+	return e.Clear(ctx)
+}
+
+// DeleteExpired removes expired tokens if the adapted store is a Sweeper.
+func (a *storeAdapter) DeleteExpired(now time.Time) (int, error) {
+	if s, ok := a.store.(Sweeper); ok {
+		return s.DeleteExpired(now)
+	}
+	return 0, nil
+}
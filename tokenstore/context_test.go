@@ -0,0 +1,130 @@
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+// mapStore is a minimal Store that is not Enumerable.
+type mapStore map[string]Token
+
+func (m mapStore) Put(u *url.URL, token Token) error {
+	m[u.String()] = token
+	return nil
+}
+
+func (m mapStore) Get(u *url.URL) (Token, bool) {
+	token, ok := m[u.String()]
+	return token, ok
+}
+
+func (m mapStore) Delete(u *url.URL) error {
+	delete(m, u.String())
+	return nil
+}
+
+// TestEnumerableStores verifies List, DeleteHost and Clear on every store of the package.
+func TestEnumerableStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"InMemoryStore": func(t *testing.T) Store {
+			return NewInMemoryStore()
+		},
+		"FileStore": func(t *testing.T) Store {
+			store, _ := newTestFileStore(t)
+			return store
+		},
+		"SQLiteStore": func(t *testing.T) Store {
+			return newTestSQLiteStore(t, filepath.Join(t.TempDir(), "tokens.db"))
+		},
+		"EncryptedStore": func(t *testing.T) Store {
+			store, err := NewEncryptedStore(NewInMemoryStore(), newTestKey(1))
+			if err != nil {
+				t.Fatalf("Failed to create encrypted store: %v", err)
+			}
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewContextStore(newStore(t))
+
+			for _, raw := range []string{"http://b.com/two", "http://a.com/one", "http://b.com/one"} {
+				u, _ := url.Parse(raw)
+				if err := store.Put(ctx, u, NewToken(raw)); err != nil {
+					t.Fatalf("Failed to put token: %v", err)
+				}
+			}
+
+			records, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("Failed to list tokens: %v", err)
+			}
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Token.Value)
+			}
+			want := []string{"http://a.com/one", "http://b.com/one", "http://b.com/two"}
+			if len(got) != len(want) {
+				t.Fatalf("Expected tokens %v, got %v", want, got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("Expected tokens %v, got %v", want, got)
+					break
+				}
+			}
+
+			if err := store.DeleteHost(ctx, "b.com"); err != nil {
+				t.Fatalf("Failed to delete host: %v", err)
+			}
+			if records, _ := store.List(ctx); len(records) != 1 || records[0].Host != "a.com" {
+				t.Errorf("Expected only a.com to be left, got %+v", records)
+			}
+
+			if err := store.Clear(ctx); err != nil {
+				t.Fatalf("Failed to clear store: %v", err)
+			}
+			if records, _ := store.List(ctx); len(records) != 0 {
+				t.Errorf("Expected no tokens after clearing, got %+v", records)
+			}
+		})
+	}
+}
+
+// TestContextStoreAdapter verifies how the adapter handles plain stores, contexts and lookup errors.
+func TestContextStoreAdapter(t *testing.T) {
+	u, _ := url.Parse("http://host.com/path")
+
+	store := NewContextStore(mapStore{})
+	ctx := context.Background()
+	if err := store.Put(ctx, u, NewToken("token123")); err != nil {
+		t.Fatalf("Failed to put token: %v", err)
+	}
+	if got, ok, err := store.Get(ctx, u); err != nil || !ok || got.Value != "token123" {
+		t.Errorf("Expected token123, got %v (%v)", got, err)
+	}
+	if _, err := store.List(ctx); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for a store that cannot list, got %v", err)
+	}
+	if err := store.Clear(ctx); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported for a store that cannot clear, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := store.Get(cancelled, u); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	underlying := NewInMemoryStore()
+	_ = underlying.Put(u, NewToken("plaintext"))
+	encrypted, _ := NewEncryptedStore(underlying, newTestKey(1))
+	if _, _, err := NewContextStore(encrypted).Get(ctx, u); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Expected ErrDecryptionFailed to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package tokenstore
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KeySize is the size of the keys used by EncryptedStore.
+	KeySize = chacha20poly1305.KeySize
+
+	// SaltSize is the size of the salts generated by NewSalt.
+	SaltSize = 16
+
+	// encryptedPrefix marks token values encrypted by EncryptedStore, followed by the key ID.
+	encryptedPrefix = "enc:v1:"
+
+	// keyIDSize is the number of bytes of the key hash used to identify a key.
+	keyIDSize = 4
+)
+
+// ErrDecryptionFailed is returned when a stored token cannot be decrypted, because it was
+// encrypted with an unknown key, was tampered with, or was stored without encryption.
+var ErrDecryptionFailed = errors.New("unable to decrypt token")
+
+// EncryptedStore encrypts token values with XChaCha20-Poly1305 before passing them to an
+// underlying store, so tokens are not kept in plaintext at rest. Token metadata is not encrypted.
+//
+// Keys can be rotated by creating the store with a new key and passing the previous keys as old
+// keys: new tokens are encrypted with the new key, and tokens encrypted with any of the keys can be read.
+// Rotate re-encrypts the stored tokens with the new key, after which the old keys can be dropped.
+//
+// Ciphertexts are bound to the host and path they are stored for, so a token value cannot be moved
+// to another URL in the underlying store.
+//
+// The underlying store only sees ciphertexts, so it cannot share tokens between paths based on their
+// caveats. Tokens are only returned for the path they were stored for, for the longest stored prefix
+// of it if its caveats do not reject the URL, or host-wide with WithHostFallback. Unlike the other
+// stores, shorter prefixes are not tried when the caveats of the longest one reject the URL.
+type EncryptedStore struct {
+	store   Store
+	current *encryptionKey
+	keys    map[string]*encryptionKey // Key ID to key, including the current key
+}
+
+type encryptionKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewEncryptedStore wraps store so token values are encrypted with key. Tokens encrypted with
+// any of oldKeys can still be read. All keys must be KeySize bytes.
+func NewEncryptedStore(store Store, key []byte, oldKeys ...[]byte) (*EncryptedStore, error) {
+	es := &EncryptedStore{
+		store: store,
+		keys:  make(map[string]*encryptionKey),
+	}
+
+	for i, k := range append([][]byte{key}, oldKeys...) {
+		if len(k) != KeySize {
+			return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(k))
+		}
+		aead, err := chacha20poly1305.NewX(k)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.Sum256(k)
+		ek := &encryptionKey{id: hex.EncodeToString(hash[:keyIDSize]), aead: aead}
+		if i == 0 {
+			es.current = ek
+		}
+		if _, exists := es.keys[ek.id]; !exists {
+			es.keys[ek.id] = ek
+		}
+	}
+
+	return es, nil
+}
+
+// NewSalt generates a random salt for DeriveKey. The salt is not secret, but has to be
+// kept alongside the encrypted tokens to derive the same key again.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("unable to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// DeriveKey derives an encryption key from a passphrase and salt with scrypt.
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is required")
+	}
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("salt is required")
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, KeySize)
+}
+
+// Put encrypts the token value and saves the token in the underlying store.
+func (es *EncryptedStore) Put(u *url.URL, token Token) error {
+	encrypted, err := es.encrypt(token.Value, u.Host, u.Path)
+	if err != nil {
+		return err
+	}
+	token.Value = encrypted
+	return es.store.Put(u, token)
+}
+
+// Get looks for a token in the underlying store and decrypts it.
+// Tokens that cannot be decrypted are reported as missing; use Lookup to get the error.
+func (es *EncryptedStore) Get(u *url.URL) (Token, bool) {
+	token, ok, err := es.Lookup(u)
+	if err != nil {
+		return Token{}, false
+	}
+	return token, ok
+}
+
+// Lookup is like Get but returns an error wrapping ErrDecryptionFailed when the stored token
+// cannot be decrypted.
+func (es *EncryptedStore) Lookup(u *url.URL) (Token, bool, error) {
+	record, ok, err := es.Match(context.Background(), u)
+	return record.Token, ok, err
+}
+
+// Match is like Lookup, but also returns the host and path the token is stored for.
+// If the underlying store is not a Matcher, the token is assumed to be stored for u.
+func (es *EncryptedStore) Match(ctx context.Context, u *url.URL) (Record, bool, error) {
+	var record Record
+	if m, ok := es.store.(Matcher); ok {
+		found, ok, err := m.Match(ctx, u)
+		if err != nil || !ok {
+			return Record{}, false, err
+		}
+		record = found
+	} else {
+		token, ok := es.store.Get(u)
+		if !ok {
+			return Record{}, false, nil
+		}
+		record = Record{Host: u.Host, Path: u.Path, Token: token}
+	}
+
+	value, err := es.decrypt(record.Token.Value, record.Host, record.Path)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("token for %s%s: %w", record.Host, record.Path, err)
+	}
+	record.Token.Value = value
+
+	// The underlying store cannot check the caveats of a token found by prefix, so check them here.
+	// The store has already settled on this token, so no shorter prefix is tried.
+	if record.Path != u.Path && rejects(record.Token, u) {
+		return Record{}, false, nil
+	}
+	return record, true, nil
+}
+
+// Delete removes a token from the underlying store.
+func (es *EncryptedStore) Delete(u *url.URL) error {
+	return es.store.Delete(u)
+}
+
+// tokenAAD binds ciphertexts to their use as the L402 token stored for host and path.
+func tokenAAD(host, path string) []byte {
+	return []byte("gol402 token " + host + path)
+}
+
+// encrypt seals value for host and path with the current key into
+// "enc:v1:<key id>:<base64 nonce and ciphertext>".
+func (es *EncryptedStore) encrypt(value, host, path string) (string, error) {
+	nonce := make([]byte, es.current.aead.NonceSize(), es.current.aead.NonceSize()+len(value)+es.current.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("unable to generate nonce: %w", err)
+	}
+
+	sealed := es.current.aead.Seal(nonce, nonce, []byte(value), tokenAAD(host, path))
+	return encryptedPrefix + es.current.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value sealed by encrypt for host and path with the key it names.
+func (es *EncryptedStore) decrypt(value, host, path string) (string, error) {
+	keyID, encoded, err := splitCiphertext(value)
+	if err != nil {
+		return "", err
+	}
+	key, ok := es.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: encrypted with unknown key %s", ErrDecryptionFailed, keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < key.aead.NonceSize() {
+		return "", fmt.Errorf("%w: malformed ciphertext", ErrDecryptionFailed)
+	}
+
+	nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+	plaintext, err := key.aead.Open(nil, nonce, ciphertext, tokenAAD(host, path))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+	return string(plaintext), nil
+}
+
+// splitCiphertext splits a value sealed by encrypt into its key ID and encoded ciphertext.
+func splitCiphertext(value string) (string, string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return "", "", fmt.Errorf("%w: token is not encrypted", ErrDecryptionFailed)
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !ok {
+		return "", "", fmt.Errorf("%w: malformed ciphertext", ErrDecryptionFailed)
+	}
+	return keyID, encoded, nil
+}
+
+// Rotate re-encrypts the tokens of the underlying store that are not encrypted with the current key,
+// and returns how many were re-encrypted. Once it succeeds, the old keys are no longer needed.
+// It fails with ErrNotSupported if the underlying store is not Enumerable.
+func (es *EncryptedStore) Rotate(ctx context.Context) (int, error) {
+	e, ok := es.store.(Enumerable)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+
+	records, err := e.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, record := range records {
+		keyID, _, err := splitCiphertext(record.Token.Value)
+		if err == nil && keyID == es.current.id {
+			continue
+		}
+
+		value, err := es.decrypt(record.Token.Value, record.Host, record.Path)
+		if err != nil {
+			return rotated, fmt.Errorf("token for %s%s: %w", record.Host, record.Path, err)
+		}
+		token := record.Token
+		token.Value = value
+		if err := es.Put(&url.URL{Host: record.Host, Path: record.Path}, token); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+// DeleteExpired removes expired tokens from the underlying store, if it is a Sweeper.
+func (es *EncryptedStore) DeleteExpired(now time.Time) (int, error) {
+	if sweeper, ok := es.store.(Sweeper); ok {
+		return sweeper.DeleteExpired(now)
+	}
+	return 0, nil
+}
+
+// List returns all tokens of the underlying store with their values decrypted.
+// It fails with ErrNotSupported if the underlying store is not Enumerable.
+func (es *EncryptedStore) List(ctx context.Context) ([]Record, error) {
+	e, ok := es.store.(Enumerable)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	records, err := e.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		value, err := es.decrypt(records[i].Token.Value, records[i].Host, records[i].Path)
+		if err != nil {
+			return nil, fmt.Errorf("token for %s%s: %w", records[i].Host, records[i].Path, err)
+		}
+		records[i].Token.Value = value
+	}
+	return records, nil
+}
+
+// DeleteHost removes all tokens stored for host from the underlying store.
+func (es *EncryptedStore) DeleteHost(ctx context.Context, host string) error {
+	e, ok := es.store.(Enumerable)
+	if !ok {
+		return ErrNotSupported
+	}
+	return e.DeleteHost(ctx, host)
+}
+
+// Clear removes all tokens from the underlying store.
+func (es *EncryptedStore) Clear(ctx context.Context) error {
+	e, ok := es.store.(Enumerable)
+	if !ok {
+		return ErrNotSupported
+	}
+	return e.Clear(ctx)
+}
@@ -0,0 +1,238 @@
+package tokenstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sulusolutions/gol402/macaroons"
+)
+
+func newTestKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+// TestEncryptedStoreRoundTrip verifies that tokens are encrypted at rest and decrypted on retrieval.
+func TestEncryptedStoreRoundTrip(t *testing.T) {
+	underlying := NewInMemoryStore()
+	store, err := NewEncryptedStore(underlying, newTestKey(1))
+	if err != nil {
+		t.Fatalf("Failed to create encrypted store: %v", err)
+	}
+
+	u, _ := url.Parse("http://host.com/path")
+	want := Token{Value: "L402 mac:preimage", AmountPaid: 1000, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := store.Put(u, want); err != nil {
+		t.Fatalf("Failed to put token: %v", err)
+	}
+
+	raw, _ := underlying.Get(u)
+	if strings.Contains(raw.Value, "preimage") || !strings.HasPrefix(raw.Value, encryptedPrefix) {
+		t.Errorf("Expected an encrypted value in the underlying store, got %q", raw.Value)
+	}
+	if raw.AmountPaid != want.AmountPaid {
+		t.Errorf("Expected metadata to be kept, got %+v", raw)
+	}
+
+	got, ok := store.Get(u)
+	if !ok || got != want {
+		t.Errorf("Expected token %+v, got %+v", want, got)
+	}
+
+	if err := store.Delete(u); err != nil {
+		t.Fatalf("Failed to delete token: %v", err)
+	}
+	if _, ok := store.Get(u); ok {
+		t.Errorf("Expected no token after deletion")
+	}
+}
+
+// TestEncryptedStoreKeyRotation verifies that tokens encrypted with old keys remain readable
+// and that new tokens use the new key.
+func TestEncryptedStoreKeyRotation(t *testing.T) {
+	underlying := NewInMemoryStore()
+	oldURL, _ := url.Parse("http://host.com/old")
+	newURL, _ := url.Parse("http://host.com/new")
+
+	oldStore, _ := NewEncryptedStore(underlying, newTestKey(1))
+	_ = oldStore.Put(oldURL, NewToken("old token"))
+
+	rotated, err := NewEncryptedStore(underlying, newTestKey(2), newTestKey(1))
+	if err != nil {
+		t.Fatalf("Failed to create rotated store: %v", err)
+	}
+	if got, ok := rotated.Get(oldURL); !ok || got.Value != "old token" {
+		t.Errorf("Expected token encrypted with the old key to be readable, got %v", got)
+	}
+	_ = rotated.Put(newURL, NewToken("new token"))
+
+	// Once the old key is dropped, only tokens written after the rotation can be read.
+	newOnly, _ := NewEncryptedStore(underlying, newTestKey(2))
+	if got, ok := newOnly.Get(newURL); !ok || got.Value != "new token" {
+		t.Errorf("Expected token encrypted with the new key to be readable, got %v", got)
+	}
+	if _, _, err := newOnly.Lookup(oldURL); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Expected ErrDecryptionFailed for a token encrypted with a dropped key, got %v", err)
+	}
+}
+
+// TestEncryptedStoreRotate verifies that Rotate re-encrypts tokens with the current key,
+// so the old keys can be dropped.
+func TestEncryptedStoreRotate(t *testing.T) {
+	underlying := NewInMemoryStore()
+	oldURL, _ := url.Parse("http://host.com/old")
+	newURL, _ := url.Parse("http://other.com/new")
+
+	oldStore, _ := NewEncryptedStore(underlying, newTestKey(1))
+	want := Token{Value: "old token", AmountPaid: 1000}
+	_ = oldStore.Put(oldURL, want)
+
+	store, _ := NewEncryptedStore(underlying, newTestKey(2), newTestKey(1))
+	_ = store.Put(newURL, NewToken("new token"))
+
+	rotated, err := store.Rotate(context.Background())
+	if err != nil || rotated != 1 {
+		t.Fatalf("Expected 1 token to be rotated, got %d: %v", rotated, err)
+	}
+	if rotated, err := store.Rotate(context.Background()); err != nil || rotated != 0 {
+		t.Errorf("Expected nothing left to rotate, got %d: %v", rotated, err)
+	}
+
+	newOnly, _ := NewEncryptedStore(underlying, newTestKey(2))
+	if got, ok, err := newOnly.Lookup(oldURL); !ok || got != want {
+		t.Errorf("Expected the rotated token %+v to be readable with the new key, got %+v: %v", want, got, err)
+	}
+	if got, ok := newOnly.Get(newURL); !ok || got.Value != "new token" {
+		t.Errorf("Expected token encrypted with the new key to be readable, got %v", got)
+	}
+
+	if _, err := newOnly.Rotate(context.Background()); err != nil {
+		t.Errorf("Expected no error rotating an up-to-date store, got %v", err)
+	}
+	unreadable, _ := NewEncryptedStore(underlying, newTestKey(3))
+	if _, err := unreadable.Rotate(context.Background()); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Expected ErrDecryptionFailed rotating tokens encrypted with an unknown key, got %v", err)
+	}
+}
+
+// TestEncryptedStoreDecryptionErrors verifies the errors for values that cannot be decrypted.
+func TestEncryptedStoreDecryptionErrors(t *testing.T) {
+	underlying := NewInMemoryStore()
+	store, _ := NewEncryptedStore(underlying, newTestKey(1))
+	u, _ := url.Parse("http://host.com/path")
+
+	_ = store.Put(u, NewToken("token123"))
+	encrypted, _ := underlying.Get(u)
+	tampered := encrypted.Value[:len(encrypted.Value)-4] + "AAA="
+
+	other, _ := url.Parse("http://other.com/path")
+	_ = store.Put(other, NewToken("token123"))
+	moved, _ := underlying.Get(other)
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"Plaintext", "L402 mac:preimage"},
+		{"Malformed", encryptedPrefix + "garbage"},
+		{"Tampered", tampered},
+		{"Moved from another URL", moved.Value},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = underlying.Put(u, NewToken(tt.value))
+
+			_, ok, err := store.Lookup(u)
+			if ok || !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("Expected ErrDecryptionFailed, got %v", err)
+			}
+			if _, ok := store.Get(u); ok {
+				t.Errorf("Expected Get to report a token that cannot be decrypted as missing")
+			}
+		})
+	}
+}
+
+// TestDeriveKey verifies that keys derived from passphrases are stable and salted.
+func TestDeriveKey(t *testing.T) {
+	salt, err := NewSalt()
+	if err != nil {
+		t.Fatalf("Failed to generate salt: %v", err)
+	}
+	otherSalt, _ := NewSalt()
+
+	key, err := DeriveKey("correct horse battery staple", salt)
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+	again, _ := DeriveKey("correct horse battery staple", salt)
+	salted, _ := DeriveKey("correct horse battery staple", otherSalt)
+
+	if len(key) != KeySize {
+		t.Errorf("Expected a %d byte key, got %d", KeySize, len(key))
+	}
+	if !bytes.Equal(key, again) {
+		t.Errorf("Expected the same passphrase and salt to derive the same key")
+	}
+	if bytes.Equal(key, salted) {
+		t.Errorf("Expected different salts to derive different keys")
+	}
+
+	if _, err := DeriveKey("", salt); err == nil {
+		t.Errorf("Expected an error for an empty passphrase")
+	}
+	if _, err := NewEncryptedStore(NewInMemoryStore(), []byte("short")); err == nil {
+		t.Errorf("Expected an error for a short key")
+	}
+}
+
+// TestEncryptedStorePrefixCaveats verifies that a token found by prefix is not returned for
+// paths its caveats reject, although the underlying store cannot read them.
+func TestEncryptedStorePrefixCaveats(t *testing.T) {
+	es, err := NewEncryptedStore(NewInMemoryStore(), newTestKey(1))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	token := newScopedToken(t, macaroons.NewPathsCaveat("/api/public"))
+	if err := es.Put(&url.URL{Host: "host.com", Path: "/api"}, token); err != nil {
+		t.Fatalf("Failed to put token: %v", err)
+	}
+
+	if got, ok := es.Get(&url.URL{Host: "host.com", Path: "/api/public/x"}); !ok || got.Value != token.Value {
+		t.Errorf("Expected token for an authorized path, got %v", got)
+	}
+	if got, ok := es.Get(&url.URL{Host: "host.com", Path: "/api/admin"}); ok {
+		t.Errorf("Expected no token for a rejected path, got %v", got)
+	}
+}
+
+// TestEncryptedStoreRejectedLongestPrefix verifies that, unlike the underlying store, EncryptedStore
+// does not fall back to a shorter prefix when the caveats of the longest prefix reject the URL.
+func TestEncryptedStoreRejectedLongestPrefix(t *testing.T) {
+	underlying := NewInMemoryStore()
+	es, err := NewEncryptedStore(underlying, newTestKey(1))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	plain := NewInMemoryStore()
+
+	shorter := newScopedToken(t, macaroons.NewPathsCaveat("/api/v1"))
+	longer := newScopedToken(t, macaroons.NewPathsCaveat("/api/v1/public"))
+	for _, store := range []Store{es, plain} {
+		_ = store.Put(&url.URL{Host: "host.com", Path: "/api"}, shorter)
+		_ = store.Put(&url.URL{Host: "host.com", Path: "/api/v1"}, longer)
+	}
+
+	u := &url.URL{Host: "host.com", Path: "/api/v1/admin"}
+	if got, ok := plain.Get(u); !ok || got.Value != shorter.Value {
+		t.Errorf("Expected the plain store to fall back to the shorter prefix, got %v", got)
+	}
+	if got, ok := es.Get(u); ok {
+		t.Errorf("Expected no token when the longest prefix is rejected, got %v", got)
+	}
+}
@@ -0,0 +1,310 @@
+package tokenstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// fileVersion is the version of the FileStore file format.
+const fileVersion = 1
+
+// ErrCorruptFile is returned when the token file cannot be parsed. The file is left untouched
+// so no tokens are lost; it has to be repaired or removed before the store can write to it again.
+var ErrCorruptFile = errors.New("corrupt token file")
+
+// FileStore persists tokens in a JSON file, so purchased tokens survive restarts.
+//
+// Every operation reads the file under a lock file shared with other processes, and changes are
+// written to a temporary file that atomically replaces the original, so a crash never leaves a
+// partially written file behind. The file is created with 0600 permissions, as tokens are bearer credentials.
+type FileStore struct {
+	path string
+	lock *flock.Flock
+	mu   sync.Mutex
+	opts options
+	now  func() time.Time
+}
+
+// fileContents is the layout of the token file.
+type fileContents struct {
+	Version int          `json:"version"`
+	Tokens  []fileRecord `json:"tokens"`
+}
+
+// fileRecord is a token in the token file.
+type fileRecord struct {
+	Host        string     `json:"host"`
+	Path        string     `json:"path"`
+	Value       string     `json:"value"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	AmountPaid  uint64     `json:"amount_paid_msat,omitempty"`
+	PaymentHash string     `json:"payment_hash,omitempty"`
+}
+
+// NewFileStore creates a store backed by the file at path, creating its directory if needed.
+// The file itself is created on the first Put. A lock file is kept next to it at path + ".lock".
+func NewFileStore(path string, opts ...Option) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create token directory: %w", err)
+	}
+
+	return &FileStore{
+		path: path,
+		lock: flock.New(path + ".lock"),
+		opts: newOptions(opts),
+		now:  time.Now,
+	}, nil
+}
+
+// Put saves a token against a specified host and path from the URL.
+func (s *FileStore) Put(u *url.URL, token Token) error {
+	return s.update(func(hosts map[string]map[string]Token) {
+		if _, exists := hosts[u.Host]; !exists {
+			hosts[u.Host] = make(map[string]Token)
+		}
+		hosts[u.Host][u.Path] = token
+	})
+}
+
+// Get looks for an unexpired token that matches the given URL, like InMemoryStore.Get.
+// Errors reading the file are reported as a missing token.
+func (s *FileStore) Get(u *url.URL) (Token, bool) {
+	record, ok, err := s.Match(context.Background(), u)
+	if err != nil {
+		return Token{}, false
+	}
+	return record.Token, ok
+}
+
+// Match is like Get, but also returns the host and path the token is stored for,
+// and reports errors reading the file.
+func (s *FileStore) Match(ctx context.Context, u *url.URL) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.lock.RLock(); err != nil {
+		return Record{}, false, fmt.Errorf("unable to lock token file: %w", err)
+	}
+	defer s.lock.Unlock() //nolint:errcheck
+
+	hosts, err := s.read()
+	if err != nil {
+		return Record{}, false, err
+	}
+	if paths, hostExists := hosts[u.Host]; hostExists {
+		record, ok := s.opts.match(paths, u, s.now())
+		return record, ok, nil
+	}
+	return Record{}, false, nil
+}
+
+// Delete removes a token that matches the given URL.
+func (s *FileStore) Delete(u *url.URL) error {
+	return s.update(func(hosts map[string]map[string]Token) {
+		if paths, hostExists := hosts[u.Host]; hostExists {
+			delete(paths, u.Path)
+			if len(paths) == 0 {
+				delete(hosts, u.Host)
+			}
+		}
+	})
+}
+
+// DeleteExpired removes the tokens that have expired at now.
+func (s *FileStore) DeleteExpired(now time.Time) (int, error) {
+	removed := 0
+	err := s.update(func(hosts map[string]map[string]Token) {
+		removed = deleteExpired(hosts, now)
+	})
+	return removed, err
+}
+
+// List returns all stored tokens, including expired ones, ordered by host and path.
+func (s *FileStore) List(ctx context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.lock.RLock(); err != nil {
+		return nil, fmt.Errorf("unable to lock token file: %w", err)
+	}
+	defer s.lock.Unlock() //nolint:errcheck
+
+	hosts, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return listRecords(hosts), nil
+}
+
+// DeleteHost removes all tokens stored for host.
+func (s *FileStore) DeleteHost(ctx context.Context, host string) error {
+	return s.update(func(hosts map[string]map[string]Token) {
+		delete(hosts, host)
+	})
+}
+
+// Clear removes all tokens.
+func (s *FileStore) Clear(ctx context.Context) error {
+	return s.update(func(hosts map[string]map[string]Token) {
+		for host := range hosts {
+			delete(hosts, host)
+		}
+	})
+}
+
+// update applies fn to the tokens in the file while holding the lock exclusively and writes the result back.
+func (s *FileStore) update(fn func(hosts map[string]map[string]Token)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.lock.Lock(); err != nil {
+		return fmt.Errorf("unable to lock token file: %w", err)
+	}
+	defer s.lock.Unlock() //nolint:errcheck
+
+	hosts, err := s.read()
+	if err != nil {
+		return err
+	}
+	fn(hosts)
+	return s.write(hosts)
+}
+
+// read loads the tokens from the file. A missing file holds no tokens.
+func (s *FileStore) read() (map[string]map[string]Token, error) {
+	hosts := make(map[string]map[string]Token)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return hosts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token file: %w", err)
+	}
+
+	var contents fileContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+	}
+	if contents.Version != fileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrCorruptFile, contents.Version)
+	}
+
+	for _, record := range contents.Tokens {
+		token, err := record.token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorruptFile, err)
+		}
+		if _, exists := hosts[record.Host]; !exists {
+			hosts[record.Host] = make(map[string]Token)
+		}
+		hosts[record.Host][record.Path] = token
+	}
+	return hosts, nil
+}
+
+// write atomically replaces the file with the given tokens.
+func (s *FileStore) write(hosts map[string]map[string]Token) error {
+	contents := fileContents{Version: fileVersion, Tokens: []fileRecord{}}
+	for host, paths := range hosts {
+		for path, token := range paths {
+			contents.Tokens = append(contents.Tokens, newFileRecord(host, path, token))
+		}
+	}
+
+	data, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0o600)
+}
+
+func newFileRecord(host, path string, token Token) fileRecord {
+	record := fileRecord{
+		Host:       host,
+		Path:       path,
+		Value:      token.Value,
+		AmountPaid: uint64(token.AmountPaid),
+	}
+	if !token.CreatedAt.IsZero() {
+		record.CreatedAt = &token.CreatedAt
+	}
+	if !token.ExpiresAt.IsZero() {
+		record.ExpiresAt = &token.ExpiresAt
+	}
+	if token.PaymentHash != (lntypes.Hash{}) {
+		record.PaymentHash = token.PaymentHash.String()
+	}
+	return record
+}
+
+func (r fileRecord) token() (Token, error) {
+	token := Token{
+		Value:      r.Value,
+		AmountPaid: lnwire.MilliSatoshi(r.AmountPaid),
+	}
+	if r.CreatedAt != nil {
+		token.CreatedAt = *r.CreatedAt
+	}
+	if r.ExpiresAt != nil {
+		token.ExpiresAt = *r.ExpiresAt
+	}
+	if r.PaymentHash != "" {
+		hash, err := lntypes.MakeHashFromStr(r.PaymentHash)
+		if err != nil {
+			return Token{}, fmt.Errorf("invalid payment hash of token for %s%s: %w", r.Host, r.Path, err)
+		}
+		token.PaymentHash = hash
+	}
+	return token, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of path, flushes it to disk
+// and renames it over path, so readers see either the old or the new contents.
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary token file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write token file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write token file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write token file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write token file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to replace token file: %w", err)
+	}
+
+	// Persist the rename itself. Not every platform supports syncing directories, so this is best effort.
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
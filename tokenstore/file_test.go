@@ -0,0 +1,178 @@
+package tokenstore
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+)
+
+func newTestFileStore(t *testing.T, opts ...Option) (*FileStore, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tokens", "tokens.json")
+	store, err := NewFileStore(path, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+	return store, path
+}
+
+// TestFileStorePersistence verifies that tokens and their metadata survive reopening the store.
+func TestFileStorePersistence(t *testing.T) {
+	store, path := newTestFileStore(t)
+	u, _ := url.Parse("http://host.com/path")
+	want := Token{
+		Value:       "L402 mac:preimage",
+		CreatedAt:   time.Unix(1700000000, 0).UTC(),
+		ExpiresAt:   time.Now().Add(time.Hour).Truncate(time.Second).UTC(),
+		AmountPaid:  21000,
+		PaymentHash: lntypes.Hash{1, 2, 3},
+	}
+
+	if err := store.Put(u, want); err != nil {
+		t.Fatalf("Failed to put token: %v", err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen file store: %v", err)
+	}
+	got, ok := reopened.Get(u)
+	if !ok {
+		t.Fatalf("Token not found after reopening the store")
+	}
+	if got.Value != want.Value || !got.CreatedAt.Equal(want.CreatedAt) || !got.ExpiresAt.Equal(want.ExpiresAt) ||
+		got.AmountPaid != want.AmountPaid || got.PaymentHash != want.PaymentHash {
+		t.Errorf("Expected token %+v, got %+v", want, got)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat token file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected token file permissions 0600, got %o", perm)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Failed to read token directory: %v", err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != "tokens.json" && name != "tokens.json.lock" {
+			t.Errorf("Unexpected file %q left in token directory", name)
+		}
+	}
+}
+
+// TestFileStoreDelete verifies deleting tokens and expired tokens.
+func TestFileStoreDelete(t *testing.T) {
+	store, _ := newTestFileStore(t)
+	now := time.Now()
+	live, _ := url.Parse("http://host.com/live")
+	expired, _ := url.Parse("http://host.com/expired")
+	other, _ := url.Parse("http://other.com/path")
+
+	_ = store.Put(live, Token{Value: "live"})
+	_ = store.Put(expired, Token{Value: "expired", ExpiresAt: now.Add(-time.Minute)})
+	_ = store.Put(other, NewToken("other"))
+
+	if _, ok := store.Get(expired); ok {
+		t.Errorf("Expected expired token to be skipped")
+	}
+
+	removed, err := store.DeleteExpired(now)
+	if err != nil || removed != 1 {
+		t.Errorf("Expected 1 expired token to be removed, got %d (%v)", removed, err)
+	}
+
+	if err := store.Delete(other); err != nil {
+		t.Fatalf("Failed to delete token: %v", err)
+	}
+	if _, ok := store.Get(other); ok {
+		t.Errorf("Expected no token after deletion")
+	}
+	if got, ok := store.Get(live); !ok || got.Value != "live" {
+		t.Errorf("Expected live token to be unaffected, got %v", got)
+	}
+}
+
+// TestFileStoreHostFallback verifies that the lookup options apply to the file store.
+func TestFileStoreHostFallback(t *testing.T) {
+	putURL, _ := url.Parse("http://host.com/path")
+	getURL, _ := url.Parse("http://host.com/anotherpath")
+
+	store, _ := newTestFileStore(t)
+	_ = store.Put(putURL, NewToken("token123"))
+	if _, ok := store.Get(getURL); ok {
+		t.Errorf("Expected no token for another path without host fallback")
+	}
+
+	store, _ = newTestFileStore(t, WithHostFallback())
+	_ = store.Put(putURL, NewToken("token123"))
+	if got, ok := store.Get(getURL); !ok || got.Value != "token123" {
+		t.Errorf("Expected host fallback token, got %v", got)
+	}
+}
+
+// TestFileStoreConcurrentWriters verifies that concurrent writes through separate store
+// instances, as used by separate processes, do not lose tokens.
+func TestFileStoreConcurrentWriters(t *testing.T) {
+	_, path := newTestFileStore(t)
+	stores := make([]*FileStore, 4)
+	for i := range stores {
+		store, err := NewFileStore(path)
+		if err != nil {
+			t.Fatalf("Failed to create file store: %v", err)
+		}
+		stores[i] = store
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u, _ := url.Parse(fmt.Sprintf("http://host.com/path%d", i))
+			if err := stores[i%len(stores)].Put(u, NewToken(fmt.Sprint("token", i))); err != nil {
+				t.Errorf("Failed to put token: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 40; i++ {
+		u, _ := url.Parse(fmt.Sprintf("http://host.com/path%d", i))
+		if got, ok := stores[0].Get(u); !ok || got.Value != fmt.Sprint("token", i) {
+			t.Errorf("Expected token%d for %v, got %v", i, u, got)
+		}
+	}
+}
+
+// TestFileStoreCorruptFile verifies that a corrupt file is reported and left untouched.
+func TestFileStoreCorruptFile(t *testing.T) {
+	store, path := newTestFileStore(t)
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("Failed to write corrupt file: %v", err)
+	}
+
+	u, _ := url.Parse("http://host.com/path")
+	if err := store.Put(u, NewToken("token123")); !errors.Is(err, ErrCorruptFile) {
+		t.Errorf("Expected ErrCorruptFile, got %v", err)
+	}
+	if _, ok := store.Get(u); ok {
+		t.Errorf("Expected no token from a corrupt file")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "{not json" {
+		t.Errorf("Expected corrupt file to be left untouched, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,171 @@
+package tokenstore
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sulusolutions/gol402/macaroons"
+)
+
+// MatchPolicy controls which stored tokens Get considers for a URL without an exact match.
+type MatchPolicy int
+
+const (
+	// MatchPrefix returns the token stored for the longest path that is a prefix of the requested
+	// path, matching whole segments, or else a token of the host whose caveats authorize the URL.
+	// Tokens whose caveats reject the URL are never returned for a prefix, and a token stored for
+	// the root path is only returned for the root path. This is the default.
+	MatchPrefix MatchPolicy = iota
+
+	// MatchExact only returns the token stored for the exact path.
+	MatchExact
+
+	// MatchHost is like MatchPrefix, but a token stored for the root path is a prefix of every
+	// path, and it falls back to any token of the host, preferring the one whose path shares the
+	// longest prefix with the requested path.
+	MatchHost
+)
+
+// options holds the lookup behaviour shared by the stores.
+type options struct {
+	policy MatchPolicy
+}
+
+// Option configures the lookup behaviour of a store.
+type Option func(*options)
+
+// WithMatchPolicy sets how tokens are matched to URLs without an exact match.
+func WithMatchPolicy(policy MatchPolicy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithHostFallback makes Get return a token stored for any path of the host when no token
+// matches or is authorized for the requested path. This sends tokens to endpoints they were
+// not bought for, so it should only be enabled for hosts that accept one token everywhere.
+// It is the same as WithMatchPolicy(MatchHost).
+func WithHostFallback() Option {
+	return WithMatchPolicy(MatchHost)
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// match picks the token for u among the tokens of its host, keyed by path, according to the
+// match policy, and returns it with the path it is stored for. Expired tokens are skipped, and
+// ties are broken by path so results are stable.
+func (o options) match(paths map[string]Token, u *url.URL, now time.Time) (Record, bool) {
+	found := func(p string) (Record, bool) {
+		return Record{Host: u.Host, Path: p, Token: paths[p]}, true
+	}
+
+	// Attempt to get the exact path match first
+	if token, pathExists := paths[u.Path]; pathExists && !token.Expired(now) {
+		return found(u.Path)
+	}
+	if o.policy == MatchExact {
+		return Record{}, false
+	}
+
+	// Otherwise consider the other tokens of the host in a stable order
+	candidates := make([]string, 0, len(paths))
+	for p, token := range paths {
+		if p != u.Path && !token.Expired(now) {
+			candidates = append(candidates, p)
+		}
+	}
+	sort.Strings(candidates)
+
+	// The token for the longest stored path that is a prefix of the requested path,
+	// unless its caveats scope it away from the requested path
+	best := ""
+	prefixFound := false
+	for _, p := range candidates {
+		if isRoot(p) && o.policy != MatchHost {
+			continue
+		}
+		if macaroons.MatchPath(p, u.Path) && (!prefixFound || len(p) > len(best)) && !rejects(paths[p], u) {
+			best, prefixFound = p, true
+		}
+	}
+	if prefixFound {
+		return found(best)
+	}
+
+	for _, p := range candidates {
+		if Authorizes(paths[p], u) {
+			return found(p)
+		}
+	}
+
+	if o.policy == MatchHost && len(candidates) > 0 {
+		best = candidates[0]
+		for _, p := range candidates[1:] {
+			if commonSegments(p, u.Path) > commonSegments(best, u.Path) {
+				best = p
+			}
+		}
+		return found(best)
+	}
+
+	return Record{}, false
+}
+
+// isRoot reports whether p is the root path.
+func isRoot(p string) bool {
+	return p == "" || p == "/"
+}
+
+// commonSegments returns the number of leading path segments a and b have in common.
+func commonSegments(a, b string) int {
+	as := strings.Split(strings.Trim(a, "/"), "/")
+	bs := strings.Split(strings.Trim(b, "/"), "/")
+
+	n := 0
+	for n < len(as) && n < len(bs) && as[n] == bs[n] {
+		n++
+	}
+	return n
+}
+
+// deleteExpired removes the tokens that have expired at now from a host to path to token map.
+func deleteExpired(hosts map[string]map[string]Token, now time.Time) int {
+	removed := 0
+	for host, paths := range hosts {
+		for path, token := range paths {
+			if token.Expired(now) {
+				delete(paths, path)
+				removed++
+			}
+		}
+		if len(paths) == 0 {
+			delete(hosts, host)
+		}
+	}
+	return removed
+}
+
+// listRecords returns the tokens of a host to path to token map, ordered by host and path.
+func listRecords(hosts map[string]map[string]Token) []Record {
+	var records []Record
+	for host, paths := range hosts {
+		for path, token := range paths {
+			records = append(records, Record{Host: host, Path: path, Token: token})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Host != records[j].Host {
+			return records[i].Host < records[j].Host
+		}
+		return records[i].Path < records[j].Path
+	})
+	return records
+}
@@ -0,0 +1,152 @@
+package tokenstore
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/sulusolutions/gol402/macaroons"
+)
+
+// TestMatchPolicies verifies which stored token each match policy picks.
+func TestMatchPolicies(t *testing.T) {
+	stored := []string{"/", "/api", "/api/v1", "/api/v1/items/42", "/apis", "/docs/guide"}
+
+	tests := []struct {
+		name   string
+		policy MatchPolicy
+		path   string
+		want   string // Path the returned token was stored for; empty for no token
+	}{
+		{"Exact match", MatchPrefix, "/api/v1", "/api/v1"},
+		{"Longest prefix", MatchPrefix, "/api/v1/items", "/api/v1"},
+		{"Prefix matches whole segments", MatchPrefix, "/apis/x", "/apis"},
+		{"Root is exact only", MatchPrefix, "/other", ""},
+		{"Root is exact only for nested paths", MatchPrefix, "/admin/users", ""},
+		{"Root under host policy", MatchHost, "/other", "/"},
+		{"Exact policy", MatchExact, "/api/v1/items", ""},
+		{"Exact policy with exact match", MatchExact, "/api", "/api"},
+		{"Host policy prefers prefix", MatchHost, "/api/v2", "/api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewInMemoryStore(WithMatchPolicy(tt.policy))
+			for _, p := range stored {
+				_ = store.Put(&url.URL{Host: "host.com", Path: p}, NewToken(p))
+			}
+
+			got, ok := store.Get(&url.URL{Host: "host.com", Path: tt.path})
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Expected no token, got %v", got)
+				}
+				return
+			}
+			if !ok || got.Value != tt.want {
+				t.Errorf("Expected token stored for %q, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestMatchHostFallback verifies that host-wide fallback picks the token sharing the longest
+// path prefix, and only when no prefix matches.
+func TestMatchHostFallback(t *testing.T) {
+	store := NewInMemoryStore(WithMatchPolicy(MatchHost))
+	for _, p := range []string{"/a/x", "/b/c/d", "/b/x"} {
+		_ = store.Put(&url.URL{Host: "host.com", Path: p}, NewToken(p))
+	}
+
+	tests := map[string]string{
+		"/b/c/e": "/b/c/d",
+		"/b/y":   "/b/c/d",
+		"/z":     "/a/x",
+	}
+	for path, want := range tests {
+		got, ok := store.Get(&url.URL{Host: "host.com", Path: path})
+		if !ok || got.Value != want {
+			t.Errorf("For %q, expected token stored for %q, got %v", path, want, got)
+		}
+	}
+
+	prefixOnly := NewInMemoryStore()
+	_ = prefixOnly.Put(&url.URL{Host: "host.com", Path: "/a/x"}, NewToken("/a/x"))
+	if got, ok := prefixOnly.Get(&url.URL{Host: "host.com", Path: "/z"}); ok {
+		t.Errorf("Expected no token without host fallback, got %v", got)
+	}
+}
+
+// TestMatchIsDeterministic verifies that lookups return the same token every time,
+// regardless of map iteration order.
+func TestMatchIsDeterministic(t *testing.T) {
+	for _, policy := range []MatchPolicy{MatchPrefix, MatchHost} {
+		store := NewInMemoryStore(WithMatchPolicy(policy))
+		for _, p := range []string{"/a", "/b", "/c", "/d", "/e", "/f", "/g", "/h"} {
+			_ = store.Put(&url.URL{Host: "host.com", Path: p + "/x"}, NewToken(p))
+			_ = store.Put(&url.URL{Host: "host.com", Path: p}, NewToken(p))
+		}
+
+		u := &url.URL{Host: "host.com", Path: "/z"}
+		first, _ := store.Get(u)
+		for i := 0; i < 100; i++ {
+			if got, _ := store.Get(u); got != first {
+				t.Fatalf("Policy %d: expected stable result %v, got %v", policy, first, got)
+			}
+		}
+
+		u = &url.URL{Host: "host.com", Path: "/c/x/y"}
+		for i := 0; i < 100; i++ {
+			if got, ok := store.Get(u); !ok || got.Value != "/c" {
+				t.Fatalf("Policy %d: expected token for /c/x, got %v", policy, got)
+			}
+		}
+	}
+}
+
+// TestMatchPrefixRespectsCaveats verifies that a token found by prefix is not returned for
+// paths its caveats do not authorize.
+func TestMatchPrefixRespectsCaveats(t *testing.T) {
+	store := NewInMemoryStore()
+	public := newScopedToken(t, macaroons.NewPathsCaveat("/api/public"))
+	_ = store.Put(&url.URL{Host: "host.com", Path: "/api"}, public)
+
+	if got, ok := store.Get(&url.URL{Host: "host.com", Path: "/api/public/items"}); !ok || got != public {
+		t.Errorf("Expected scoped token for an authorized path, got %v", got)
+	}
+	if got, ok := store.Get(&url.URL{Host: "host.com", Path: "/api/admin"}); ok {
+		t.Errorf("Expected no token for a path the caveats reject, got %v", got)
+	}
+
+	// A shorter unscoped prefix is used when the longer one is rejected.
+	caveat, err := macaroons.NewServicesCaveat(macaroons.Service{Name: "svc"})
+	if err != nil {
+		t.Fatalf("Failed to create caveat: %v", err)
+	}
+	service := newScopedToken(t, caveat)
+	_ = store.Put(&url.URL{Host: "host.com", Path: "/v1"}, NewToken("plain"))
+	_ = store.Put(&url.URL{Host: "host.com", Path: "/v1/other"}, service)
+	if got, ok := store.Get(&url.URL{Host: "host.com", Path: "/v1/other/x"}); !ok || got.Value != "plain" {
+		t.Errorf("Expected unscoped token for /v1, got %v", got)
+	}
+}
+
+// TestMatchReturnsStoredPath verifies that Match reports where a prefix-matched token is stored.
+func TestMatchReturnsStoredPath(t *testing.T) {
+	store := NewInMemoryStore()
+	_ = store.Put(&url.URL{Host: "host.com", Path: "/api"}, NewToken("token"))
+
+	var m Matcher = store
+	record, ok, err := m.Match(context.Background(), &url.URL{Host: "host.com", Path: "/api/a"})
+	if err != nil || !ok {
+		t.Fatalf("Expected a match, got %v, %v", ok, err)
+	}
+	if record.Host != "host.com" || record.Path != "/api" || record.Token.Value != "token" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	adapted := NewContextStore(store).(Matcher)
+	if record, ok, _ := adapted.Match(context.Background(), &url.URL{Host: "host.com", Path: "/api/a"}); !ok || record.Path != "/api" {
+		t.Errorf("Expected the adapter to report the stored path, got %+v", record)
+	}
+}
@@ -1,19 +1,26 @@
 package tokenstore
 
 import (
+	"context"
 	"net/url"
 	"sync"
+	"time"
 )
 
+// InMemoryStore keeps tokens in memory, keyed by host and path.
 type InMemoryStore struct {
 	mu    sync.RWMutex
 	store map[string]map[string]Token // Outer map key is host, inner map key is path
+	opts  options
+	now   func() time.Time
 }
 
 // NewInMemoryStore creates a new instance of InMemoryStore.
-func NewInMemoryStore() *InMemoryStore {
+func NewInMemoryStore(opts ...Option) *InMemoryStore {
 	return &InMemoryStore{
 		store: make(map[string]map[string]Token),
+		opts:  newOptions(opts),
+		now:   time.Now,
 	}
 }
 
@@ -36,29 +43,25 @@ func (ims *InMemoryStore) Put(u *url.URL, token Token) error {
 	return nil
 }
 
-// Get looks for a token that matches the given URL.
-// It returns the most relevant token if available; otherwise, it returns nil.
+// Get looks for an unexpired token that matches the given URL.
+// It returns the token stored for the exact path, otherwise a token of the host chosen by the match policy.
 func (ims *InMemoryStore) Get(u *url.URL) (Token, bool) {
+	record, ok, _ := ims.Match(context.Background(), u)
+	return record.Token, ok
+}
+
+// Match is like Get, but also returns the host and path the token is stored for.
+func (ims *InMemoryStore) Match(ctx context.Context, u *url.URL) (Record, bool, error) {
 	ims.mu.RLock()
 	defer ims.mu.RUnlock()
 
-	host := u.Host
-	path := u.Path
-
 	// Check if host exists
-	if paths, hostExists := ims.store[host]; hostExists {
-		// Attempt to get the exact path match first
-		if token, pathExists := paths[path]; pathExists {
-			return token, true
-		}
-
-		// If no exact path match, take a token from any path under the host
-		for _, token := range paths {
-			return token, true
-		}
+	if paths, hostExists := ims.store[u.Host]; hostExists {
+		record, ok := ims.opts.match(paths, u, ims.now())
+		return record, ok, nil
 	}
 
-	return "", false
+	return Record{}, false, nil
 }
 
 // Delete removes a token that matches the given URL.
@@ -82,3 +85,37 @@ func (ims *InMemoryStore) Delete(u *url.URL) error {
 
 	return nil
 }
+
+// DeleteExpired removes the tokens that have expired at now.
+func (ims *InMemoryStore) DeleteExpired(now time.Time) (int, error) {
+	ims.mu.Lock()
+	defer ims.mu.Unlock()
+
+	return deleteExpired(ims.store, now), nil
+}
+
+// List returns all stored tokens, including expired ones, ordered by host and path.
+func (ims *InMemoryStore) List(ctx context.Context) ([]Record, error) {
+	ims.mu.RLock()
+	defer ims.mu.RUnlock()
+
+	return listRecords(ims.store), nil
+}
+
+// DeleteHost removes all tokens stored for host.
+func (ims *InMemoryStore) DeleteHost(ctx context.Context, host string) error {
+	ims.mu.Lock()
+	defer ims.mu.Unlock()
+
+	delete(ims.store, host)
+	return nil
+}
+
+// Clear removes all tokens.
+func (ims *InMemoryStore) Clear(ctx context.Context) error {
+	ims.mu.Lock()
+	defer ims.mu.Unlock()
+
+	ims.store = make(map[string]map[string]Token)
+	return nil
+}
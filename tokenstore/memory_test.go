@@ -5,13 +5,15 @@ import (
 	"net/url"
 	"sync"
 	"testing"
+
+	"github.com/sulusolutions/gol402/macaroons"
 )
 
 // TestPutNewToken verifies that a new token can be added successfully.
 func TestPutNewToken(t *testing.T) {
 	store := NewInMemoryStore()
 	testURL, _ := url.Parse("http://host.com/path")
-	want := Token("token123")
+	want := NewToken("token123")
 
 	err := store.Put(testURL, want)
 	if err != nil {
@@ -28,8 +30,8 @@ func TestPutNewToken(t *testing.T) {
 func TestUpdateToken(t *testing.T) {
 	store := NewInMemoryStore()
 	testURL, _ := url.Parse("http://host.com/path")
-	initialToken := Token("initialToken")
-	updatedToken := Token("updatedToken")
+	initialToken := NewToken("initialToken")
+	updatedToken := NewToken("updatedToken")
 
 	_ = store.Put(testURL, initialToken)
 
@@ -56,8 +58,8 @@ func TestPutDifferentPaths(t *testing.T) {
 		path  string
 		token Token
 	}{
-		{"/path1", Token("token1")},
-		{"/path2", Token("token2")},
+		{"/path1", NewToken("token1")},
+		{"/path2", NewToken("token2")},
 	}
 
 	// Put tokens
@@ -98,7 +100,7 @@ func TestConcurrentPut(t *testing.T) {
 			if testURL == nil {
 				t.Errorf("Failed to parse URL: %v", baseURL+fmt.Sprint(i))
 			}
-			want := Token("token" + fmt.Sprint(i))
+			want := NewToken("token" + fmt.Sprint(i))
 			_ = store.Put(testURL, want)
 		}(i)
 	}
@@ -108,7 +110,7 @@ func TestConcurrentPut(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		testURL, _ := url.Parse(baseURL + fmt.Sprint(i))
 		got, ok := store.Get(testURL)
-		want := Token("token" + fmt.Sprint(i))
+		want := NewToken("token" + fmt.Sprint(i))
 		if !ok {
 			t.Errorf("Concurrent put failed for %v: token not found", testURL)
 		}
@@ -122,7 +124,7 @@ func TestConcurrentPut(t *testing.T) {
 func TestGetTokenExactMatch(t *testing.T) {
 	store := NewInMemoryStore()
 	testURL, _ := url.Parse("http://host.com/path")
-	token := Token("token123")
+	token := NewToken("token123")
 
 	_ = store.Put(testURL, token)
 
@@ -135,12 +137,13 @@ func TestGetTokenExactMatch(t *testing.T) {
 	}
 }
 
-// TestGetTokenHostMatch verifies retrieving a token for a URL that matches only the host.
+// TestGetTokenHostMatch verifies retrieving a token for a URL that matches only the host
+// when host-wide fallback is enabled.
 func TestGetTokenHostMatch(t *testing.T) {
-	store := NewInMemoryStore()
+	store := NewInMemoryStore(WithHostFallback())
 	putURL, _ := url.Parse("http://host.com/path")
 	getURL, _ := url.Parse("http://host.com/anotherpath")
-	want := Token("token123")
+	want := NewToken("token123")
 
 	_ = store.Put(putURL, want)
 
@@ -150,6 +153,42 @@ func TestGetTokenHostMatch(t *testing.T) {
 	}
 }
 
+// TestGetTokenHostMatchWithoutFallback verifies that tokens are not sent to other paths of
+// the host unless their caveats authorize it.
+func TestGetTokenHostMatchWithoutFallback(t *testing.T) {
+	store := NewInMemoryStore()
+	putURL, _ := url.Parse("http://host.com/randomnumber")
+	getURL, _ := url.Parse("http://host.com/admin")
+
+	_ = store.Put(putURL, NewToken("token123"))
+
+	got, ok := store.Get(getURL)
+	if ok {
+		t.Errorf("Expected no token for another path without host fallback, got %v", got)
+	}
+}
+
+// TestGetTokenScoped verifies that a token is returned for another path of the host
+// when its caveats authorize that path.
+func TestGetTokenScoped(t *testing.T) {
+	store := NewInMemoryStore()
+	putURL, _ := url.Parse("http://host.com/randomnumber")
+	want := newScopedToken(t, macaroons.NewCaveat("services", "randomnumber:0"))
+
+	_ = store.Put(putURL, want)
+
+	getURL, _ := url.Parse("http://host.com/randomnumber/v2")
+	got, ok := store.Get(getURL)
+	if !ok || got != want {
+		t.Errorf("Expected to retrieve scoped token %v, got %v", want, got)
+	}
+
+	getURL, _ = url.Parse("http://host.com/admin")
+	if got, ok := store.Get(getURL); ok {
+		t.Errorf("Expected no token for a path outside the token scope, got %v", got)
+	}
+}
+
 // TestGetTokenNoMatch verifies that no token is retrieved for a URL with no match.
 func TestGetTokenNoMatch(t *testing.T) {
 	store := NewInMemoryStore()
@@ -168,7 +207,7 @@ func TestConcurrentGet(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to parse URL: %v", err)
 	}
-	want := Token("token123")
+	want := NewToken("token123")
 	_ = store.Put(u, want)
 
 	var wg sync.WaitGroup
@@ -194,7 +233,7 @@ func TestConcurrentGet(t *testing.T) {
 func TestDeleteExistingToken(t *testing.T) {
 	store := NewInMemoryStore()
 	testURL, _ := url.Parse("http://host.com/path")
-	want := Token("token123")
+	want := NewToken("token123")
 
 	_ = store.Put(testURL, want)
 	_ = store.Delete(testURL)
@@ -218,10 +257,10 @@ func TestDeleteNonExistentToken(t *testing.T) {
 
 // TestDeleteEffectOnOtherTokens verifies that deleting a token does not affect other tokens.
 func TestDeleteEffectOnOtherTokens(t *testing.T) {
-	store := NewInMemoryStore()
+	store := NewInMemoryStore(WithHostFallback())
 	host := "http://host.com"
 	firstPath, secondPath := "/path1", "/path2"
-	firstToken, secondToken := Token("token1"), Token("token2")
+	firstToken, secondToken := NewToken("token1"), NewToken("token2")
 
 	// Store two tokens under the same host but different paths
 	if err := store.Put(&url.URL{Host: host, Path: firstPath}, firstToken); err != nil {
@@ -258,7 +297,7 @@ func TestDeleteEffectOnOtherTokens(t *testing.T) {
 func TestDeleteHostLevelCleanup(t *testing.T) {
 	store := NewInMemoryStore()
 	testURL, _ := url.Parse("http://host.com/path")
-	want := Token("token123")
+	want := NewToken("token123")
 
 	_ = store.Put(testURL, want)
 	_ = store.Delete(testURL)
@@ -279,7 +318,7 @@ func TestConcurrentDelete(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to parse URL: %v", err)
 	}
-	want := Token("token123")
+	want := NewToken("token123")
 	_ = store.Put(baseURL, want)
 
 	var wg sync.WaitGroup
@@ -1,6 +1,9 @@
 package tokenstore
 
-import "net/url"
+import (
+	"context"
+	"net/url"
+)
 
 type NoOpStore struct{}
 
@@ -13,9 +16,21 @@ func (s *NoOpStore) Put(u *url.URL, token Token) error {
 }
 
 func (s *NoOpStore) Get(u *url.URL) (Token, bool) {
-	return "", false
+	return Token{}, false
 }
 
 func (s *NoOpStore) Delete(u *url.URL) error {
 	return nil
 }
+
+func (s *NoOpStore) List(ctx context.Context) ([]Record, error) {
+	return nil, nil
+}
+
+func (s *NoOpStore) DeleteHost(ctx context.Context, host string) error {
+	return nil
+}
+
+func (s *NoOpStore) Clear(ctx context.Context) error {
+	return nil
+}
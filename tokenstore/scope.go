@@ -0,0 +1,120 @@
+package tokenstore
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/sulusolutions/gol402/macaroons"
+	"gopkg.in/macaroon.v2"
+)
+
+// Authorizes reports whether the caveats of the macaroon in an L402 token authorize a request to u,
+// so the token can be reused for URLs other than the one it was bought for.
+//
+// A token is scoped to u by a paths caveat with a prefix of the URL path, or by a services caveat
+// naming a service that appears as a segment of the URL path. Every paths and services caveat must
+// allow u, and if the token carries capabilities for a matched service, the path segment following
+// the service name must be one of them. Opaque tokens and tokens without such caveats authorize nothing.
+func Authorizes(token Token, u *url.URL) bool {
+	mac, ok := tokenMacaroon(token)
+	if !ok {
+		return false
+	}
+
+	var caveats []macaroons.Caveat
+	for _, c := range mac.Caveats() {
+		if caveat, err := macaroons.ParseCaveat(string(c.Id)); err == nil {
+			caveats = append(caveats, caveat)
+		}
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	scoped := false
+	matched := make(map[string]int) // Service name to the index of its path segment
+
+	for _, caveat := range caveats {
+		switch caveat.Condition {
+		case macaroons.PathsCondition:
+			if macaroons.PathsSatisfier(u.Path).Satisfy(caveat.Value) != nil {
+				return false
+			}
+			scoped = true
+
+		case macaroons.ServicesCondition:
+			services, err := macaroons.ParseServices(caveat.Value)
+			if err != nil {
+				return false
+			}
+			found := false
+			for _, service := range services {
+				if i := indexOf(segments, service.Name); i >= 0 {
+					matched[service.Name] = i
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+			scoped = true
+		}
+	}
+
+	for service, i := range matched {
+		if i+1 >= len(segments) {
+			continue
+		}
+		capabilities := macaroons.CapabilitiesSatisfier(service, segments[i+1])
+		for _, caveat := range caveats {
+			if caveat.Condition == capabilities.Condition && capabilities.Satisfy(caveat.Value) != nil {
+				return false
+			}
+		}
+	}
+
+	return scoped
+}
+
+// rejects reports whether the token is scoped by paths, services or capabilities caveats
+// that do not authorize a request to u.
+func rejects(token Token, u *url.URL) bool {
+	mac, ok := tokenMacaroon(token)
+	if !ok {
+		return false
+	}
+
+	for _, c := range mac.Caveats() {
+		caveat, err := macaroons.ParseCaveat(string(c.Id))
+		if err != nil {
+			continue
+		}
+		if caveat.Condition == macaroons.PathsCondition || caveat.Condition == macaroons.ServicesCondition ||
+			strings.HasSuffix(caveat.Condition, macaroons.CapabilitiesCondition("")) {
+			return !Authorizes(token, u)
+		}
+	}
+	return false
+}
+
+// tokenMacaroon decodes the macaroon of a token of the form "L402 <macaroon>:<preimage>".
+func tokenMacaroon(token Token) (*macaroon.Macaroon, bool) {
+	credentials := token.Value
+	if _, rest, ok := strings.Cut(credentials, " "); ok {
+		credentials = rest
+	}
+	encoded, _, _ := strings.Cut(credentials, ":")
+
+	mac, err := macaroons.Decode(encoded)
+	if err != nil {
+		return nil, false
+	}
+	return mac, true
+}
+
+func indexOf(segments []string, name string) int {
+	for i, segment := range segments {
+		if segment == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,71 @@
+package tokenstore
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/sulusolutions/gol402/macaroons"
+)
+
+// newScopedToken returns an L402 token whose macaroon carries the given caveats.
+func newScopedToken(t *testing.T, caveats ...macaroons.Caveat) Token {
+	t.Helper()
+
+	rootKeys, err := macaroons.NewStaticRootKeyStore([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Failed to create root key store: %v", err)
+	}
+	mac, err := macaroons.Mint(context.Background(), rootKeys, lntypes.Hash{}, "", caveats...)
+	if err != nil {
+		t.Fatalf("Failed to mint macaroon: %v", err)
+	}
+	encoded, err := macaroons.Encode(mac)
+	if err != nil {
+		t.Fatalf("Failed to encode macaroon: %v", err)
+	}
+	return NewToken("L402 " + encoded + ":" + lntypes.Preimage{}.String())
+}
+
+// TestAuthorizes verifies which URLs the caveats of a token authorize.
+func TestAuthorizes(t *testing.T) {
+	tests := []struct {
+		name  string
+		token Token
+		path  string
+		want  bool
+	}{
+		{"Opaque token", NewToken("token123"), "/randomnumber", false},
+		{"No scoping caveats", newScopedToken(t), "/randomnumber", false},
+		{"Service in path", newScopedToken(t, macaroons.NewCaveat("services", "randomnumber:0")), "/api/randomnumber", true},
+		{"Service not in path", newScopedToken(t, macaroons.NewCaveat("services", "randomnumber:0")), "/admin", false},
+		{"One of several services", newScopedToken(t, macaroons.NewCaveat("services", "a:0,b:0")), "/b/x", true},
+		{"Path prefix", newScopedToken(t, macaroons.NewPathsCaveat("/api")), "/api/v1/items", true},
+		{"Path segment mismatch", newScopedToken(t, macaroons.NewPathsCaveat("/api")), "/apis", false},
+		{
+			"Path allowed but service not",
+			newScopedToken(t, macaroons.NewPathsCaveat("/api"), macaroons.NewCaveat("services", "other:0")),
+			"/api/randomnumber", false,
+		},
+		{
+			"Capability granted",
+			newScopedToken(t, macaroons.NewCaveat("services", "svc:0"), macaroons.NewCapabilitiesCaveat("svc", "read")),
+			"/svc/read", true,
+		},
+		{
+			"Capability not granted",
+			newScopedToken(t, macaroons.NewCaveat("services", "svc:0"), macaroons.NewCapabilitiesCaveat("svc", "read")),
+			"/svc/write", false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &url.URL{Scheme: "https", Host: "host.com", Path: tt.path}
+			if got := Authorizes(tt.token, u); got != tt.want {
+				t.Errorf("Authorizes(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package tokenstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+
+	// Register the pure-Go SQLite driver.
+	_ "modernc.org/sqlite"
+)
+
+// migrations are applied in order to bring the database schema up to date.
+// The schema version is kept in the user_version pragma. Released migrations must never be changed.
+var migrations = []string{
+	`CREATE TABLE tokens (
+		host TEXT NOT NULL,
+		path TEXT NOT NULL,
+		value TEXT NOT NULL,
+		created_at INTEGER,
+		expires_at INTEGER,
+		amount_msat INTEGER NOT NULL DEFAULT 0,
+		payment_hash TEXT,
+		last_used_at INTEGER,
+		PRIMARY KEY (host, path)
+	);
+	CREATE INDEX tokens_expires_at ON tokens (expires_at);`,
+
+	// payments records every token bought and is never updated, so replacing or deleting tokens
+	// does not change how much was spent. Payments are identified by their payment hash or, for
+	// tokens without one, by their host, path and creation time (0 if unknown).
+	`CREATE TABLE payments (
+		id INTEGER PRIMARY KEY,
+		host TEXT NOT NULL,
+		path TEXT NOT NULL,
+		amount_msat INTEGER NOT NULL,
+		payment_hash TEXT UNIQUE,
+		paid_at INTEGER NOT NULL
+	);
+	CREATE UNIQUE INDEX payments_unhashed ON payments (host, path, paid_at) WHERE payment_hash IS NULL;
+	CREATE INDEX payments_host ON payments (host);
+	INSERT OR IGNORE INTO payments (host, path, amount_msat, payment_hash, paid_at)
+		SELECT host, path, amount_msat, payment_hash, COALESCE(created_at, 0) FROM tokens WHERE amount_msat > 0;`,
+}
+
+// SQLiteStore persists tokens in a SQLite database, for clients that accumulate many tokens.
+// It uses a pure-Go driver, so no cgo is required. The database runs in WAL mode with a busy
+// timeout, so several processes can use the same file.
+type SQLiteStore struct {
+	db   *sql.DB
+	opts options
+	now  func() time.Time
+}
+
+// NewSQLiteStore opens or creates the SQLite database at path and migrates it to the latest schema.
+func NewSQLiteStore(path string, opts ...Option) (*SQLiteStore, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open token database: %w", err)
+	}
+
+	if err := migrate(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{
+		db:   db,
+		opts: newOptions(opts),
+		now:  time.Now,
+	}, nil
+}
+
+// migrate applies the migrations the database has not seen yet, each in its own transaction.
+func migrate(ctx context.Context, db *sql.DB) error {
+	for {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("unable to migrate token database: %w", err)
+		}
+
+		var version int
+		if err := tx.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return fmt.Errorf("unable to read token database version: %w", err)
+		}
+		if version > len(migrations) {
+			tx.Rollback() //nolint:errcheck
+			return fmt.Errorf("token database version %d is newer than supported version %d", version, len(migrations))
+		}
+		if version == len(migrations) {
+			return tx.Rollback()
+		}
+
+		if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return fmt.Errorf("unable to apply token database migration %d: %w", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return fmt.Errorf("unable to apply token database migration %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("unable to apply token database migration %d: %w", version+1, err)
+		}
+	}
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Put saves a token against a specified host and path from the URL, replacing any previous token.
+// Tokens with an amount paid are also recorded as payments for SpentPerHost, once per payment hash,
+// or for tokens without one, once per host, path and creation time.
+func (s *SQLiteStore) Put(u *url.URL, token Token) error {
+	var paymentHash sql.NullString
+	if token.PaymentHash != (lntypes.Hash{}) {
+		paymentHash = sql.NullString{String: token.PaymentHash.String(), Valid: true}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to store token: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	_, err = tx.Exec(`
+		INSERT INTO tokens (host, path, value, created_at, expires_at, amount_msat, payment_hash, last_used_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULL)
+		ON CONFLICT (host, path) DO UPDATE SET
+			value = excluded.value,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at,
+			amount_msat = excluded.amount_msat,
+			payment_hash = excluded.payment_hash,
+			last_used_at = NULL`,
+		u.Host, u.Path, token.Value, nullTime(token.CreatedAt), nullTime(token.ExpiresAt),
+		int64(token.AmountPaid), paymentHash,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to store token: %w", err)
+	}
+
+	if token.AmountPaid > 0 {
+		_, err = tx.Exec(`
+			INSERT OR IGNORE INTO payments (host, path, amount_msat, payment_hash, paid_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			u.Host, u.Path, int64(token.AmountPaid), paymentHash, nullTime(token.CreatedAt).Int64,
+		)
+		if err != nil {
+			return fmt.Errorf("unable to record payment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to store token: %w", err)
+	}
+	return nil
+}
+
+// Get looks for an unexpired token that matches the given URL, like InMemoryStore.Get,
+// and records that it was used. Database errors are reported as a missing token.
+func (s *SQLiteStore) Get(u *url.URL) (Token, bool) {
+	record, ok, err := s.Match(context.Background(), u)
+	if err != nil {
+		return Token{}, false
+	}
+	return record.Token, ok
+}
+
+// Match is like Get, but also returns the host and path the token is stored for,
+// and reports database errors.
+func (s *SQLiteStore) Match(ctx context.Context, u *url.URL) (Record, bool, error) {
+	now := s.now()
+
+	records, err := s.query(ctx,
+		"WHERE host = ? AND (expires_at IS NULL OR expires_at > ?)", u.Host, now.UnixNano())
+	if err != nil {
+		return Record{}, false, err
+	}
+
+	paths := make(map[string]Token, len(records))
+	for _, record := range records {
+		paths[record.Path] = record.Token
+	}
+	record, ok := s.opts.match(paths, u, now)
+	if !ok {
+		return Record{}, false, nil
+	}
+
+	// Failing to record usage does not make the token unusable.
+	_, _ = s.db.ExecContext(ctx, "UPDATE tokens SET last_used_at = ? WHERE host = ? AND path = ?",
+		now.UnixNano(), record.Host, record.Path)
+	record.LastUsedAt = now
+	return record, true, nil
+}
+
+// Delete removes a token that matches the given URL.
+func (s *SQLiteStore) Delete(u *url.URL) error {
+	if _, err := s.db.Exec("DELETE FROM tokens WHERE host = ? AND path = ?", u.Host, u.Path); err != nil {
+		return fmt.Errorf("unable to delete token: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes the tokens that have expired at now.
+func (s *SQLiteStore) DeleteExpired(now time.Time) (int, error) {
+	result, err := s.db.Exec("DELETE FROM tokens WHERE expires_at IS NOT NULL AND expires_at <= ?", now.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete expired tokens: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
+
+// List returns all stored tokens, including expired ones, ordered by host and path.
+func (s *SQLiteStore) List(ctx context.Context) ([]Record, error) {
+	return s.query(ctx, "")
+}
+
+// DeleteHost removes all tokens stored for host.
+func (s *SQLiteStore) DeleteHost(ctx context.Context, host string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM tokens WHERE host = ?", host); err != nil {
+		return fmt.Errorf("unable to delete tokens: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all tokens.
+func (s *SQLiteStore) Clear(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM tokens"); err != nil {
+		return fmt.Errorf("unable to delete tokens: %w", err)
+	}
+	return nil
+}
+
+// TokensForHost returns the tokens stored for host, including expired ones, ordered by path.
+func (s *SQLiteStore) TokensForHost(ctx context.Context, host string) ([]Record, error) {
+	return s.query(ctx, "WHERE host = ?", host)
+}
+
+// SpentPerHost returns the total amount paid for tokens, per host, including tokens that have
+// since been replaced or deleted.
+func (s *SQLiteStore) SpentPerHost(ctx context.Context) (map[string]lnwire.MilliSatoshi, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT host, SUM(amount_msat) FROM payments GROUP BY host")
+	if err != nil {
+		return nil, fmt.Errorf("unable to query spending: %w", err)
+	}
+	defer rows.Close()
+
+	spent := make(map[string]lnwire.MilliSatoshi)
+	for rows.Next() {
+		var host string
+		var amount int64
+		if err := rows.Scan(&host, &amount); err != nil {
+			return nil, fmt.Errorf("unable to query spending: %w", err)
+		}
+		spent[host] = lnwire.MilliSatoshi(amount)
+	}
+	return spent, rows.Err()
+}
+
+// query returns the records matching the given WHERE clause, ordered by host and path.
+func (s *SQLiteStore) query(ctx context.Context, where string, args ...any) ([]Record, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT host, path, value, created_at, expires_at, amount_msat, payment_hash, last_used_at
+		FROM tokens `+where+` ORDER BY host, path`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var (
+			record                         Record
+			createdAt, expiresAt, lastUsed sql.NullInt64
+			amount                         int64
+			paymentHash                    sql.NullString
+		)
+		err := rows.Scan(&record.Host, &record.Path, &record.Token.Value, &createdAt, &expiresAt,
+			&amount, &paymentHash, &lastUsed)
+		if err != nil {
+			return nil, fmt.Errorf("unable to query tokens: %w", err)
+		}
+
+		record.Token.CreatedAt = fromNullTime(createdAt)
+		record.Token.ExpiresAt = fromNullTime(expiresAt)
+		record.Token.AmountPaid = lnwire.MilliSatoshi(amount)
+		record.LastUsedAt = fromNullTime(lastUsed)
+		if paymentHash.Valid {
+			hash, err := lntypes.MakeHashFromStr(paymentHash.String)
+			if err != nil {
+				return nil, fmt.Errorf("invalid payment hash of token for %s%s: %w", record.Host, record.Path, err)
+			}
+			record.Token.PaymentHash = hash
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query tokens: %w", err)
+	}
+	return records, nil
+}
+
+// nullTime stores times as Unix nanoseconds, and the zero time as NULL.
+func nullTime(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+func fromNullTime(n sql.NullInt64) time.Time {
+	if !n.Valid {
+		return time.Time{}
+	}
+	return time.Unix(0, n.Int64)
+}
@@ -0,0 +1,207 @@
+package tokenstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+func newTestSQLiteStore(t *testing.T, path string, opts ...Option) *SQLiteStore {
+	t.Helper()
+
+	store, err := NewSQLiteStore(path, opts...)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// TestSQLiteStorePersistence verifies that tokens and their metadata survive reopening the database.
+func TestSQLiteStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.db")
+	store := newTestSQLiteStore(t, path)
+
+	u, _ := url.Parse("http://host.com/path")
+	want := Token{
+		Value:       "L402 mac:preimage",
+		CreatedAt:   time.Unix(1700000000, 0),
+		ExpiresAt:   time.Now().Add(time.Hour).Truncate(time.Second),
+		AmountPaid:  21000,
+		PaymentHash: lntypes.Hash{1, 2, 3},
+	}
+	if err := store.Put(u, want); err != nil {
+		t.Fatalf("Failed to put token: %v", err)
+	}
+	store.Close()
+
+	// Reopening runs the migrations again, which must be a no-op.
+	reopened := newTestSQLiteStore(t, path)
+	got, ok := reopened.Get(u)
+	if !ok {
+		t.Fatalf("Token not found after reopening the database")
+	}
+	if got.Value != want.Value || !got.CreatedAt.Equal(want.CreatedAt) || !got.ExpiresAt.Equal(want.ExpiresAt) ||
+		got.AmountPaid != want.AmountPaid || got.PaymentHash != want.PaymentHash {
+		t.Errorf("Expected token %+v, got %+v", want, got)
+	}
+
+	records, err := reopened.TokensForHost(context.Background(), "host.com")
+	if err != nil {
+		t.Fatalf("Failed to query tokens: %v", err)
+	}
+	if len(records) != 1 || records[0].Path != "/path" || records[0].LastUsedAt.IsZero() {
+		t.Errorf("Expected one used token for host.com, got %+v", records)
+	}
+}
+
+// TestSQLiteStoreLookup verifies updates, deletion, expiry and the lookup options.
+func TestSQLiteStoreLookup(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newTestSQLiteStore(t, filepath.Join(t.TempDir(), "tokens.db"))
+	store.now = func() time.Time { return now }
+
+	first, _ := url.Parse("http://host.com/first")
+	second, _ := url.Parse("http://host.com/second")
+	expired, _ := url.Parse("http://host.com/expired")
+
+	_ = store.Put(first, NewToken("old"))
+	_ = store.Put(first, NewToken("new"))
+	if got, ok := store.Get(first); !ok || got.Value != "new" {
+		t.Errorf("Expected updated token, got %v", got)
+	}
+
+	if got, ok := store.Get(second); ok {
+		t.Errorf("Expected no token for another path without host fallback, got %v", got)
+	}
+
+	_ = store.Put(expired, Token{Value: "expired", ExpiresAt: now})
+	if _, ok := store.Get(expired); ok {
+		t.Errorf("Expected expired token to be skipped")
+	}
+	removed, err := store.DeleteExpired(now)
+	if err != nil || removed != 1 {
+		t.Errorf("Expected 1 expired token to be removed, got %d (%v)", removed, err)
+	}
+
+	_ = store.Delete(first)
+	if _, ok := store.Get(first); ok {
+		t.Errorf("Expected no token after deletion")
+	}
+
+	fallback := newTestSQLiteStore(t, filepath.Join(t.TempDir(), "tokens.db"), WithHostFallback())
+	_ = fallback.Put(first, NewToken("token123"))
+	if got, ok := fallback.Get(second); !ok || got.Value != "token123" {
+		t.Errorf("Expected host fallback token, got %v", got)
+	}
+}
+
+// TestSQLiteStoreSpentPerHost verifies the spending summary, which keeps counting tokens
+// that were replaced or deleted.
+func TestSQLiteStoreSpentPerHost(t *testing.T) {
+	store := newTestSQLiteStore(t, filepath.Join(t.TempDir(), "tokens.db"))
+
+	tokens := map[string]lnwire.MilliSatoshi{
+		"http://a.com/one": 1000,
+		"http://a.com/two": 2000,
+		"http://b.com/one": 500,
+	}
+	for raw, amount := range tokens {
+		u, _ := url.Parse(raw)
+		_ = store.Put(u, Token{Value: raw, AmountPaid: amount})
+	}
+
+	checkSpent := func(want map[string]lnwire.MilliSatoshi) {
+		t.Helper()
+		spent, err := store.SpentPerHost(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to query spending: %v", err)
+		}
+		if fmt.Sprint(spent) != fmt.Sprint(want) {
+			t.Errorf("Expected spending %v, got %v", want, spent)
+		}
+	}
+	checkSpent(map[string]lnwire.MilliSatoshi{"a.com": 3000, "b.com": 500})
+
+	// Replacing a token adds its payment, and deleting tokens does not refund them.
+	one, _ := url.Parse("http://a.com/one")
+	replacement := Token{Value: "replacement", AmountPaid: 1500, PaymentHash: lntypes.Hash{1}}
+	_ = store.Put(one, replacement)
+	_ = store.DeleteHost(context.Background(), "b.com")
+	checkSpent(map[string]lnwire.MilliSatoshi{"a.com": 4500, "b.com": 500})
+
+	// Storing a token again, e.g. re-encrypted, does not count its payment twice.
+	replacement.Value = "re-encrypted"
+	_ = store.Put(one, replacement)
+	checkSpent(map[string]lnwire.MilliSatoshi{"a.com": 4500, "b.com": 500})
+
+	// Tokens without a payment hash are identified by their URL and creation time.
+	two, _ := url.Parse("http://a.com/two")
+	hashless := Token{Value: "hashless", AmountPaid: 100, CreatedAt: time.Unix(1700000000, 0)}
+	_ = store.Put(two, hashless)
+	hashless.Value = "hashless again"
+	_ = store.Put(two, hashless)
+	checkSpent(map[string]lnwire.MilliSatoshi{"a.com": 4600, "b.com": 500})
+}
+
+// TestSQLiteStoreMigratePayments verifies that upgrading a database records the payments
+// of the tokens it already holds.
+func TestSQLiteStoreMigratePayments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(migrations[0] + `;
+		PRAGMA user_version = 1;
+		INSERT INTO tokens (host, path, value, amount_msat) VALUES ('a.com', '/one', 'token', 1000);`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to create version 1 database: %v", err)
+	}
+
+	store := newTestSQLiteStore(t, path)
+	spent, err := store.SpentPerHost(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to query spending: %v", err)
+	}
+	if spent["a.com"] != 1000 {
+		t.Errorf("Expected the payment of the existing token to be recorded, got %v", spent)
+	}
+}
+
+// TestSQLiteStoreConcurrentWriters verifies that separate connections to one database,
+// as used by separate processes, can write concurrently.
+func TestSQLiteStoreConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.db")
+	stores := []*SQLiteStore{newTestSQLiteStore(t, path), newTestSQLiteStore(t, path)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u, _ := url.Parse(fmt.Sprintf("http://host.com/path%d", i))
+			if err := stores[i%len(stores)].Put(u, NewToken(fmt.Sprint("token", i))); err != nil {
+				t.Errorf("Failed to put token: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	records, err := stores[0].TokensForHost(context.Background(), "host.com")
+	if err != nil {
+		t.Fatalf("Failed to query tokens: %v", err)
+	}
+	if len(records) != 40 {
+		t.Errorf("Expected 40 tokens, got %d", len(records))
+	}
+}
@@ -1,9 +1,54 @@
 package tokenstore
 
-import "net/url"
+import (
+	"net/url"
+	"time"
 
-// Token represents a wrapper around the L402 token string.
-type Token string
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// Token is an L402 token together with what is known about its purchase.
+type Token struct {
+	// Value is the Authorization header value, e.g. "L402 <macaroon>:<preimage>".
+	Value string
+
+	// CreatedAt is when the token was bought.
+	CreatedAt time.Time
+
+	// ExpiresAt is when the token stops being accepted. The zero time means no known expiry.
+	ExpiresAt time.Time
+
+	// AmountPaid is the amount paid for the token.
+	AmountPaid lnwire.MilliSatoshi
+
+	// PaymentHash is the payment hash of the invoice paid for the token.
+	PaymentHash lntypes.Hash
+}
+
+// NewToken creates a token without metadata from its Authorization header value.
+func NewToken(value string) Token {
+	return Token{Value: value}
+}
+
+// String returns the Authorization header value of the token.
+func (t Token) String() string {
+	return t.Value
+}
+
+// Expired reports whether the token has expired at now.
+func (t Token) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// Record is a stored token together with where and when it was used.
+// LastUsedAt is only tracked by SQLiteStore.
+type Record struct {
+	Host       string
+	Path       string
+	Token      Token
+	LastUsedAt time.Time
+}
 
 // Store defines the interface for storing and retrieving L402 tokens.
 type Store interface {
@@ -12,6 +57,7 @@ type Store interface {
 
 	// RetrieveToken looks for a token that matches the given host and path.
 	// It returns the most relevant token if available; otherwise, it returns nil.
+	// Expired tokens are not returned.
 	Get(u *url.URL) (Token, bool)
 
 	// Delete removes a token that matches the given host and path.
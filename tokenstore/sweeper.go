@@ -0,0 +1,31 @@
+package tokenstore
+
+import (
+	"context"
+	"time"
+)
+
+// Sweeper is implemented by stores that can evict expired tokens.
+type Sweeper interface {
+	// DeleteExpired removes the tokens that have expired at now and returns how many were removed.
+	DeleteExpired(now time.Time) (int, error)
+}
+
+// RunSweeper removes expired tokens from s every interval until ctx is done.
+// It is meant to be started in its own goroutine:
+//
+//	go tokenstore.RunSweeper(ctx, store, time.Minute)
+func RunSweeper(ctx context.Context, s Sweeper, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			// Errors are transient for the stores that can fail; the next tick retries.
+			_, _ = s.DeleteExpired(now)
+		}
+	}
+}
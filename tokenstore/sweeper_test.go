@@ -0,0 +1,97 @@
+package tokenstore
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestGetSkipsExpiredTokens verifies that expired tokens are never returned.
+func TestGetSkipsExpiredTokens(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewInMemoryStore(WithHostFallback())
+	store.now = func() time.Time { return now }
+
+	expiredURL, _ := url.Parse("http://host.com/expired")
+	liveURL, _ := url.Parse("http://host.com/live")
+	expired := Token{Value: "expired", ExpiresAt: now}
+	live := Token{Value: "live", ExpiresAt: now.Add(time.Minute)}
+
+	_ = store.Put(expiredURL, expired)
+	if got, ok := store.Get(expiredURL); ok {
+		t.Errorf("Expected no token for an expired exact match, got %v", got)
+	}
+
+	_ = store.Put(liveURL, live)
+	if got, ok := store.Get(expiredURL); !ok || got != live {
+		t.Errorf("Expected fallback to the live token %v, got %v", live, got)
+	}
+}
+
+// TestDeleteExpired verifies that only expired tokens are removed.
+func TestDeleteExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewInMemoryStore()
+
+	tokens := map[string]Token{
+		"http://a.com/expired":   {Value: "expired", ExpiresAt: now.Add(-time.Second)},
+		"http://b.com/expired":   {Value: "expired", ExpiresAt: now},
+		"http://b.com/live":      {Value: "live", ExpiresAt: now.Add(time.Second)},
+		"http://b.com/no-expiry": {Value: "no-expiry"},
+	}
+	for raw, token := range tokens {
+		u, _ := url.Parse(raw)
+		_ = store.Put(u, token)
+	}
+
+	removed, err := store.DeleteExpired(now)
+	if err != nil {
+		t.Fatalf("DeleteExpired failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 tokens to be removed, got %d", removed)
+	}
+	if _, exists := store.store["a.com"]; exists {
+		t.Errorf("Expected host without tokens to be removed")
+	}
+	if len(store.store["b.com"]) != 2 {
+		t.Errorf("Expected 2 tokens left for b.com, got %d", len(store.store["b.com"]))
+	}
+}
+
+// TestRunSweeper verifies that the sweeper removes expired tokens until its context is cancelled.
+func TestRunSweeper(t *testing.T) {
+	store := NewInMemoryStore()
+	u, _ := url.Parse("http://host.com/path")
+	_ = store.Put(u, Token{Value: "token123", ExpiresAt: time.Now()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunSweeper(ctx, store, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		store.mu.RLock()
+		remaining := len(store.store)
+		store.mu.RUnlock()
+		if remaining == 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("Sweeper did not remove the expired token")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sweeper did not stop after cancellation")
+	}
+}
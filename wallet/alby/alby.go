@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/sulusolutions/gol402/wallet"
 )
 
@@ -21,10 +24,13 @@ type albyPaymentResponse struct {
 	PaymentRequest  string `json:"payment_request"`
 }
 
-// AlbyWallet implements the Wallet interface using the Alby REST API.
+// AlbyWallet implements the Wallet and Invoicer interfaces using the Alby REST API.
 type AlbyWallet struct {
 	// BaseURL is the base URL for the Alby API.
 	BaseURL string
+	// PollInterval is how often SubscribeInvoice checks the invoice status.
+	// If zero, it defaults to 2 seconds.
+	PollInterval time.Duration
 	// credentials is the Bearer token for authorization.
 	credentials string
 }
@@ -45,6 +51,7 @@ func (aw *AlbyWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*
 		// Include "amount" if necessary
 	}
 
+	createdAt := time.Now()
 	responseBody, err := aw.makeRequest(ctx, "POST", path, body)
 	if err != nil {
 		return nil, err
@@ -58,6 +65,17 @@ func (aw *AlbyWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*
 	var result wallet.PaymentResult
 	result.Preimage = albyResponse.PaymentPreimage
 	result.Success = true
+	result.CreatedAt = createdAt
+	result.SettledAt = time.Now()
+
+	// Alby reports amounts in satoshis.
+	result.Amount = lnwire.MilliSatoshi(albyResponse.Amount * 1000)
+	result.Fee = lnwire.MilliSatoshi(albyResponse.Fee * 1000)
+
+	// The invoice is paid at this point, so a malformed hash only leaves it unset.
+	if hash, err := lntypes.MakeHashFromStr(albyResponse.PaymentHash); err == nil {
+		result.PaymentHash = hash
+	}
 
 	return &result, nil
 }
@@ -100,8 +118,8 @@ func (aw *AlbyWallet) makeRequest(ctx context.Context, method, path string, body
 		return nil, fmt.Errorf("error reading response body: %w", err)
 	}
 
-	// Check for non-200 status codes
-	if resp.StatusCode != http.StatusOK {
+	// Check for non-2xx status codes
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		// Here, you might want to unmarshal the response body to a structured error type, similar to the PHP example
 		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, responseBody)
 	}
@@ -45,7 +45,7 @@ func (m *MockAlbyServer) handler(w http.ResponseWriter, r *http.Request) {
 
 	// If everything is good, return a fake successful response.
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"payment_preimage": "preimage123", "success": true}`)) //nolint:errcheck
+	w.Write([]byte(`{"amount": 100, "fee": 2, "payment_hash": "f3a2d4c1f1e2d3c4b5a6978877665544332211ffeeddccbbaa99887766554433", "payment_preimage": "preimage123", "success": true}`)) //nolint:errcheck
 }
 
 func (m *MockAlbyServer) Close() {
@@ -64,10 +64,16 @@ func TestPayInvoice(t *testing.T) {
 	w := NewAlbyWallet(creds)
 	w.BaseURL = s.URL() // Point the wallet to the mock server
 
-	_, err := w.PayInvoice(context.Background(), "validInvoice")
+	result, err := w.PayInvoice(context.Background(), "validInvoice")
 	if err != nil {
 		t.Fatalf("Expected no error, but got: %v", err)
 	}
+	if result.Amount != 100000 || result.Fee != 2000 {
+		t.Errorf("Expected amount 100000 msat and fee 2000 msat, got %v and %v", result.Amount, result.Fee)
+	}
+	if result.PaymentHash.String() != "f3a2d4c1f1e2d3c4b5a6978877665544332211ffeeddccbbaa99887766554433" {
+		t.Errorf("Unexpected payment hash %v", result.PaymentHash)
+	}
 
 	// Test with incorrect token
 	w.credentials = "wrongToken"
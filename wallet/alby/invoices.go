@@ -0,0 +1,141 @@
+package alby
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// defaultPollInterval is how often SubscribeInvoice polls the Alby API if PollInterval is not set.
+const defaultPollInterval = 2 * time.Second
+
+type albyInvoiceResponse struct {
+	Amount         int64  `json:"amount"`
+	PaymentHash    string `json:"payment_hash"`
+	PaymentRequest string `json:"payment_request"`
+	Preimage       string `json:"preimage"`
+	Settled        bool   `json:"settled"`
+	SettledAt      string `json:"settled_at"`
+	State          string `json:"state"`
+}
+
+// CreateInvoice creates a new invoice in the Alby account.
+// Alby only supports whole satoshi amounts.
+func (aw *AlbyWallet) CreateInvoice(ctx context.Context, req wallet.InvoiceRequest) (*wallet.InvoiceStatus, error) {
+	if req.Amount%1000 != 0 {
+		return nil, fmt.Errorf("alby invoices must be for a whole number of satoshis, got %v", req.Amount)
+	}
+
+	body := map[string]interface{}{
+		"amount":      int64(req.Amount.ToSatoshis()),
+		"description": req.Memo,
+	}
+	if req.Expiry > 0 {
+		body["expiry"] = int64(req.Expiry.Seconds())
+	}
+
+	responseBody, err := aw.makeRequest(ctx, "POST", "/invoices", body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseInvoiceResponse(responseBody)
+}
+
+// LookupInvoice returns the status of the invoice with the given payment hash.
+func (aw *AlbyWallet) LookupInvoice(ctx context.Context, hash lntypes.Hash) (*wallet.InvoiceStatus, error) {
+	responseBody, err := aw.makeRequest(ctx, "GET", "/invoices/"+hash.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseInvoiceResponse(responseBody)
+}
+
+// SubscribeInvoice polls the Alby API for the status of the invoice with the given payment hash
+// and sends an update whenever its state changes.
+func (aw *AlbyWallet) SubscribeInvoice(ctx context.Context, hash lntypes.Hash) (<-chan wallet.InvoiceStatus, <-chan error, error) {
+	interval := aw.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	updates := make(chan wallet.InvoiceStatus)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastState := wallet.InvoiceState(-1)
+		for {
+			status, err := aw.LookupInvoice(ctx, hash)
+			if err != nil {
+				if ctx.Err() == nil {
+					errs <- err
+				}
+				return
+			}
+
+			if status.State != lastState {
+				lastState = status.State
+				select {
+				case updates <- *status:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if status.State.Final() {
+				return
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates, errs, nil
+}
+
+func parseInvoiceResponse(responseBody []byte) (*wallet.InvoiceStatus, error) {
+	var albyResponse albyInvoiceResponse
+	if err := json.Unmarshal(responseBody, &albyResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshaling Alby response: %w", err)
+	}
+
+	hash, err := lntypes.MakeHashFromStr(albyResponse.PaymentHash)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing payment hash: %w", err)
+	}
+
+	status := &wallet.InvoiceStatus{
+		PaymentRequest: wallet.Invoice(albyResponse.PaymentRequest),
+		PaymentHash:    hash,
+		State:          wallet.InvoiceStateOpen,
+	}
+
+	switch {
+	case albyResponse.Settled || strings.EqualFold(albyResponse.State, "SETTLED"):
+		status.State = wallet.InvoiceStateSettled
+		status.Preimage = albyResponse.Preimage
+		status.AmountPaid = lnwire.MilliSatoshi(albyResponse.Amount * 1000)
+		if settledAt, err := time.Parse(time.RFC3339, albyResponse.SettledAt); err == nil {
+			status.SettledAt = settledAt
+		}
+	case strings.EqualFold(albyResponse.State, "CANCELED"), strings.EqualFold(albyResponse.State, "EXPIRED"):
+		status.State = wallet.InvoiceStateCanceled
+	}
+
+	return status, nil
+}
@@ -0,0 +1,135 @@
+package alby
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+const (
+	testPaymentHash = "f3a2d4c1f1e2d3c4b5a6978877665544332211ffeeddccbbaa99887766554433"
+	testPreimage    = "0102030405060708091011121314151617181920212223242526272829303132"
+)
+
+// mockInvoiceServer mimics the invoice endpoints of the Alby API.
+// The invoice is reported as settled once it has been looked up settleAfter times.
+type mockInvoiceServer struct {
+	mu          sync.Mutex
+	lookups     int
+	settleAfter int
+	created     map[string]interface{}
+}
+
+func (m *mockInvoiceServer) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer correctToken" {
+		http.Error(w, `{"error": "Invalid or missing bearer token"}`, http.StatusUnauthorized)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/invoices":
+		if err := json.NewDecoder(r.Body).Decode(&m.created); err != nil {
+			http.Error(w, `{"error": "Invalid request"}`, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"payment_hash": "` + testPaymentHash + `", "payment_request": "lnbc10u1test", "state": "CREATED"}`)) //nolint:errcheck
+
+	case r.Method == "GET" && r.URL.Path == "/invoices/"+testPaymentHash:
+		m.lookups++
+		if m.lookups < m.settleAfter {
+			w.Write([]byte(`{"payment_hash": "` + testPaymentHash + `", "payment_request": "lnbc10u1test", "state": "CREATED"}`)) //nolint:errcheck
+			return
+		}
+		w.Write([]byte(`{"amount": 1000, "payment_hash": "` + testPaymentHash + `", "payment_request": "lnbc10u1test",` + //nolint:errcheck
+			`"preimage": "` + testPreimage + `", "settled": true, "settled_at": "2024-03-01T12:00:00Z", "state": "SETTLED"}`))
+
+	default:
+		http.Error(w, `{"error": "Not found"}`, http.StatusNotFound)
+	}
+}
+
+func newTestInvoiceWallet(t *testing.T, settleAfter int) (*AlbyWallet, *mockInvoiceServer) {
+	t.Helper()
+
+	mock := &mockInvoiceServer{settleAfter: settleAfter}
+	server := httptest.NewServer(http.HandlerFunc(mock.handler))
+	t.Cleanup(server.Close)
+
+	w := NewAlbyWallet("correctToken")
+	w.BaseURL = server.URL
+	w.PollInterval = time.Millisecond
+	return w, mock
+}
+
+func TestCreateInvoice(t *testing.T) {
+	w, mock := newTestInvoiceWallet(t, 1)
+
+	status, err := w.CreateInvoice(context.Background(), wallet.InvoiceRequest{
+		Amount: 1_000_000,
+		Memo:   "test",
+		Expiry: time.Minute,
+	})
+	require.NoError(t, err)
+	require.Equal(t, wallet.Invoice("lnbc10u1test"), status.PaymentRequest)
+	require.Equal(t, testPaymentHash, status.PaymentHash.String())
+	require.Equal(t, wallet.InvoiceStateOpen, status.State)
+
+	require.Equal(t, map[string]interface{}{"amount": 1000.0, "description": "test", "expiry": 60.0}, mock.created)
+
+	_, err = w.CreateInvoice(context.Background(), wallet.InvoiceRequest{Amount: 1500})
+	require.Error(t, err, "fractional satoshi amounts are not supported")
+}
+
+func TestLookupInvoice(t *testing.T) {
+	w, _ := newTestInvoiceWallet(t, 1)
+	hash, err := lntypes.MakeHashFromStr(testPaymentHash)
+	require.NoError(t, err)
+
+	status, err := w.LookupInvoice(context.Background(), hash)
+	require.NoError(t, err)
+	require.Equal(t, wallet.InvoiceStateSettled, status.State)
+	require.Equal(t, testPreimage, status.Preimage)
+	require.Equal(t, lnwire.MilliSatoshi(1_000_000), status.AmountPaid)
+	require.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), status.SettledAt)
+
+	w.credentials = "wrongToken"
+	_, err = w.LookupInvoice(context.Background(), hash)
+	require.Error(t, err)
+}
+
+func TestSubscribeInvoice(t *testing.T) {
+	w, _ := newTestInvoiceWallet(t, 3)
+	hash, err := lntypes.MakeHashFromStr(testPaymentHash)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updates, errs, err := w.SubscribeInvoice(ctx, hash)
+	require.NoError(t, err)
+
+	var states []wallet.InvoiceState
+	for update := range updates {
+		states = append(states, update.State)
+	}
+	require.Equal(t, []wallet.InvoiceState{wallet.InvoiceStateOpen, wallet.InvoiceStateSettled}, states)
+
+	select {
+	case err := <-errs:
+		t.Fatalf("Unexpected subscription error: %v", err)
+	default:
+	}
+}
@@ -0,0 +1,180 @@
+package cln
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// Payment states reported by the pay and listpays commands.
+const (
+	statusComplete = "complete"
+	statusPending  = "pending"
+	statusFailed   = "failed"
+)
+
+// msat is a millisatoshi amount. Core Lightning encodes amounts as integers,
+// while releases before v23 use strings such as "1000msat".
+type msat uint64
+
+func (m *msat) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSuffix(strings.Trim(string(data), `"`), "msat")
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid msat amount %s: %w", data, err)
+	}
+	*m = msat(v)
+	return nil
+}
+
+type payResponse struct {
+	PaymentHash     string  `json:"payment_hash"`
+	PaymentPreimage string  `json:"payment_preimage"`
+	AmountMsat      msat    `json:"amount_msat"`
+	AmountSentMsat  msat    `json:"amount_sent_msat"`
+	Parts           int     `json:"parts"`
+	CreatedAt       float64 `json:"created_at"`
+	Status          string  `json:"status"`
+}
+
+type listPaysResponse struct {
+	Pays []struct {
+		PaymentHash    string `json:"payment_hash"`
+		Status         string `json:"status"`
+		Preimage       string `json:"preimage"`
+		AmountMsat     *msat  `json:"amount_msat"`
+		AmountSentMsat msat   `json:"amount_sent_msat"`
+		CreatedAt      int64  `json:"created_at"`
+	} `json:"pays"`
+}
+
+// ClnWallet implements the Wallet interface using a Core Lightning node.
+type ClnWallet struct {
+	// RetryFor is how long the node keeps retrying a payment before giving up.
+	// If zero, the node's default of 60 seconds is used.
+	RetryFor time.Duration
+	// PollInterval is how often a pending payment is checked.
+	// If zero, it defaults to 2 seconds.
+	PollInterval time.Duration
+
+	rpc caller
+}
+
+// NewClnWallet creates a ClnWallet that talks JSON-RPC over the lightning-rpc
+// unix socket of a node, e.g. ~/.lightning/bitcoin/lightning-rpc.
+func NewClnWallet(socketPath string) *ClnWallet {
+	return &ClnWallet{
+		rpc: &socketCaller{path: socketPath},
+	}
+}
+
+// NewClnRestWallet creates a ClnWallet that talks to the clnrest plugin of a node
+// at baseURL, authenticating with authRune. A nil httpClient uses http.DefaultClient.
+func NewClnRestWallet(baseURL, authRune string, httpClient *http.Client) *ClnWallet {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &ClnWallet{
+		rpc: &restCaller{
+			baseURL:    baseURL,
+			authRune:   authRune,
+			httpClient: httpClient,
+		},
+	}
+}
+
+// PayInvoice pays the given invoice with the pay command and waits until the payment settles.
+// Errors reported by the node are returned as *RPCError, which matches the errors of this
+// package with errors.Is.
+func (cw *ClnWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	params := map[string]interface{}{
+		"bolt11": string(invoice),
+	}
+	if cw.RetryFor > 0 {
+		params["retry_for"] = int(cw.RetryFor.Seconds())
+	}
+
+	var resp payResponse
+	if err := cw.rpc.call(ctx, "pay", params, &resp); err != nil {
+		return nil, err
+	}
+
+	switch resp.Status {
+	case statusComplete:
+		return newPaymentResult(resp.PaymentPreimage, resp.PaymentHash, resp.AmountMsat,
+			resp.AmountSentMsat, resp.CreatedAt), nil
+	case statusPending:
+		return cw.waitPayment(ctx, resp.PaymentHash)
+	default:
+		return nil, fmt.Errorf("%w: status %q", ErrPaymentFailed, resp.Status)
+	}
+}
+
+// waitPayment polls listpays until the payment with the given hash leaves the pending state.
+func (cw *ClnWallet) waitPayment(ctx context.Context, paymentHash string) (*wallet.PaymentResult, error) {
+	interval := cw.PollInterval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		var resp listPaysResponse
+		params := map[string]interface{}{"payment_hash": paymentHash}
+		if err := cw.rpc.call(ctx, "listpays", params, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Pays) == 0 {
+			return nil, fmt.Errorf("payment %s not found", paymentHash)
+		}
+
+		pay := resp.Pays[0]
+		switch pay.Status {
+		case statusComplete:
+			var amount msat
+			if pay.AmountMsat != nil {
+				amount = *pay.AmountMsat
+			}
+			return newPaymentResult(pay.Preimage, pay.PaymentHash, amount,
+				pay.AmountSentMsat, float64(pay.CreatedAt)), nil
+		case statusFailed:
+			return nil, ErrPaymentFailed
+		}
+	}
+}
+
+// newPaymentResult creates the result of a settled payment. The fee is the difference
+// between the amount sent and the amount received by the payee, and createdAt is in
+// seconds since the epoch.
+func newPaymentResult(preimage, paymentHash string, amount, sent msat, createdAt float64) *wallet.PaymentResult {
+	result := &wallet.PaymentResult{
+		Preimage:  preimage,
+		Success:   true,
+		Amount:    lnwire.MilliSatoshi(amount),
+		SettledAt: time.Now(),
+	}
+	if createdAt > 0 {
+		result.CreatedAt = time.Unix(0, int64(createdAt*float64(time.Second)))
+	}
+	if sent > amount {
+		result.Fee = lnwire.MilliSatoshi(sent - amount)
+	}
+	if hash, err := lntypes.MakeHashFromStr(paymentHash); err == nil {
+		result.PaymentHash = hash
+	}
+	return result
+}
@@ -0,0 +1,253 @@
+package cln
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testInvoice     = "lnbcrt10u1fakeinvoice"
+	testPaymentHash = "f3a2d4c1f1e2d3c4b5a6978877665544332211ffeeddccbbaa99887766554433"
+	testPreimage    = "0102030405060708091011121314151617181920212223242526272829303132"
+)
+
+// rpcHandler answers a single RPC call of the fake node.
+type rpcHandler func(method string, params map[string]interface{}) (interface{}, *RPCError)
+
+// fakeNode serves JSON-RPC over a unix socket like the lightning-rpc socket of Core Lightning.
+type fakeNode struct {
+	listener net.Listener
+	handler  rpcHandler
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func newFakeNode(t *testing.T, handler rpcHandler) *fakeNode {
+	t.Helper()
+
+	// Unix socket paths are limited in length, so avoid the long t.TempDir paths.
+	dir, err := os.MkdirTemp("", "cln")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "lightning-rpc"))
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	node := &fakeNode{listener: listener, handler: handler}
+	go node.serve()
+	return node
+}
+
+func (n *fakeNode) serve() {
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			return
+		}
+		go n.serveConn(conn)
+	}
+}
+
+func (n *fakeNode) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	var req struct {
+		ID     uint64                 `json:"id"`
+		Method string                 `json:"method"`
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	n.mu.Lock()
+	n.calls = append(n.calls, req.Method)
+	n.mu.Unlock()
+
+	result, rpcErr := n.handler(req.Method, req.Params)
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	json.NewEncoder(conn).Encode(resp) //nolint:errcheck
+}
+
+func (n *fakeNode) path() string {
+	return n.listener.Addr().String()
+}
+
+func (n *fakeNode) methods() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.calls...)
+}
+
+func completePay(method string, params map[string]interface{}) (interface{}, *RPCError) {
+	if method != "pay" || params["bolt11"] != testInvoice {
+		return nil, &RPCError{Code: codeInvalidParams, Message: "Invalid bolt11"}
+	}
+	return map[string]interface{}{
+		"payment_hash":     testPaymentHash,
+		"payment_preimage": testPreimage,
+		"amount_msat":      1000000,
+		"amount_sent_msat": 1000010,
+		"parts":            1,
+		"created_at":       1714564800.5,
+		"status":           "complete",
+	}, nil
+}
+
+func TestClnWallet_PayInvoice(t *testing.T) {
+	node := newFakeNode(t, completePay)
+	w := NewClnWallet(node.path())
+
+	result, err := w.PayInvoice(context.Background(), testInvoice)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, testPreimage, result.Preimage)
+	require.Equal(t, testPaymentHash, result.PaymentHash.String())
+	require.Equal(t, lnwire.MilliSatoshi(1000000), result.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(10), result.Fee)
+	require.Equal(t, time.Unix(1714564800, 500000000), result.CreatedAt)
+}
+
+func TestClnWallet_PayInvoicePending(t *testing.T) {
+	var mu sync.Mutex
+	lookups := 0
+	node := newFakeNode(t, func(method string, params map[string]interface{}) (interface{}, *RPCError) {
+		switch method {
+		case "pay":
+			return map[string]interface{}{
+				"payment_hash": testPaymentHash,
+				"amount_msat":  "1000000msat",
+				"status":       "pending",
+			}, nil
+		case "listpays":
+			mu.Lock()
+			defer mu.Unlock()
+			lookups++
+
+			pay := map[string]interface{}{"payment_hash": testPaymentHash, "status": "pending"}
+			if lookups > 1 {
+				pay["status"] = "complete"
+				pay["preimage"] = testPreimage
+				pay["amount_msat"] = "1000000msat"
+				pay["amount_sent_msat"] = "1000005msat"
+			}
+			return map[string]interface{}{"pays": []interface{}{pay}}, nil
+		}
+		return nil, &RPCError{Code: -32601, Message: "Unknown command"}
+	})
+	w := NewClnWallet(node.path())
+	w.PollInterval = 10 * time.Millisecond
+
+	result, err := w.PayInvoice(context.Background(), testInvoice)
+	require.NoError(t, err)
+	require.Equal(t, testPreimage, result.Preimage)
+	require.Equal(t, lnwire.MilliSatoshi(1000000), result.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(5), result.Fee)
+	require.Equal(t, []string{"pay", "listpays", "listpays"}, node.methods())
+}
+
+func TestClnWallet_PayInvoiceErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      int
+		wantError error
+	}{
+		{"In progress", codePayInProgress, ErrPaymentInProgress},
+		{"Already paid", codePayRhashAlreadyUsed, ErrAlreadyPaid},
+		{"Destination failed", codePayDestinationFailed, ErrDestinationFailed},
+		{"No route", codePayRouteNotFound, ErrRouteNotFound},
+		{"Too expensive", codePayRouteTooExpensive, ErrRouteTooExpensive},
+		{"Expired", codePayInvoiceExpired, ErrInvoiceExpired},
+		{"Stopped retrying", codePayStoppedRetrying, ErrPaymentFailed},
+		{"Invalid invoice", codeInvalidParams, ErrInvalidInvoice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := newFakeNode(t, func(string, map[string]interface{}) (interface{}, *RPCError) {
+				return nil, &RPCError{Code: tt.code, Message: tt.name}
+			})
+
+			_, err := NewClnWallet(node.path()).PayInvoice(context.Background(), testInvoice)
+			require.ErrorIs(t, err, tt.wantError)
+
+			var rpcErr *RPCError
+			require.ErrorAs(t, err, &rpcErr)
+			require.Equal(t, tt.code, rpcErr.Code)
+		})
+	}
+}
+
+func TestClnWallet_PayInvoiceContextCanceled(t *testing.T) {
+	// The node never answers, so the call must be abandoned when the context ends.
+	node := newFakeNode(t, func(string, map[string]interface{}) (interface{}, *RPCError) {
+		time.Sleep(time.Second)
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := NewClnWallet(node.path()).PayInvoice(ctx, testInvoice)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClnRestWallet_PayInvoice(t *testing.T) {
+	const testRune = "test-rune"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Rune") != testRune {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": 1501, "message": "Not authorized"}`)) //nolint:errcheck
+			return
+		}
+		if r.Method != "POST" || r.URL.Path != "/v1/pay" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		var params map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, rpcErr := completePay("pay", params)
+		if rpcErr != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(rpcErr) //nolint:errcheck
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	result, err := NewClnRestWallet(server.URL, testRune, nil).PayInvoice(context.Background(), testInvoice)
+	require.NoError(t, err)
+	require.Equal(t, testPreimage, result.Preimage)
+
+	_, err = NewClnRestWallet(server.URL, testRune, nil).PayInvoice(context.Background(), "lnbc1garbage")
+	require.ErrorIs(t, err, ErrInvalidInvoice)
+
+	_, err = NewClnRestWallet(server.URL, "wrong", nil).PayInvoice(context.Background(), testInvoice)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, 1501, rpcErr.Code)
+}
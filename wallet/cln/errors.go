@@ -0,0 +1,79 @@
+package cln
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes returned by the pay command of Core Lightning.
+const (
+	codePayInProgress        = 200
+	codePayRhashAlreadyUsed  = 201
+	codePayDestinationFailed = 203
+	codePayRouteNotFound     = 205
+	codePayRouteTooExpensive = 206
+	codePayInvoiceExpired    = 207
+	codePayStoppedRetrying   = 210
+	codeInvalidParams        = -32602
+)
+
+var (
+	// ErrPaymentInProgress is returned when a payment of the invoice is already in flight.
+	ErrPaymentInProgress = errors.New("payment already in progress")
+
+	// ErrAlreadyPaid is returned when the invoice was already paid with different parameters.
+	ErrAlreadyPaid = errors.New("invoice already paid")
+
+	// ErrDestinationFailed is returned when the destination permanently rejected the payment.
+	ErrDestinationFailed = errors.New("destination rejected payment")
+
+	// ErrRouteNotFound is returned when no route to the destination could be found.
+	ErrRouteNotFound = errors.New("no route found")
+
+	// ErrRouteTooExpensive is returned when all routes exceed the fee or delay limits.
+	ErrRouteTooExpensive = errors.New("route too expensive")
+
+	// ErrInvoiceExpired is returned when the invoice expired before it could be paid.
+	ErrInvoiceExpired = errors.New("invoice expired")
+
+	// ErrPaymentFailed is returned when the node gave up on the payment.
+	ErrPaymentFailed = errors.New("payment failed")
+
+	// ErrInvalidInvoice is returned when the node rejects the invoice as malformed.
+	ErrInvalidInvoice = errors.New("invalid invoice")
+)
+
+// RPCError is an error returned by the Core Lightning RPC. It unwraps to one of the
+// errors of this package when its code is known, so callers can use errors.Is.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("CLN RPC error %d: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the typed error for the error code, or nil if the code is unknown.
+func (e *RPCError) Unwrap() error {
+	switch e.Code {
+	case codePayInProgress:
+		return ErrPaymentInProgress
+	case codePayRhashAlreadyUsed:
+		return ErrAlreadyPaid
+	case codePayDestinationFailed:
+		return ErrDestinationFailed
+	case codePayRouteNotFound:
+		return ErrRouteNotFound
+	case codePayRouteTooExpensive:
+		return ErrRouteTooExpensive
+	case codePayInvoiceExpired:
+		return ErrInvoiceExpired
+	case codePayStoppedRetrying:
+		return ErrPaymentFailed
+	case codeInvalidParams:
+		return ErrInvalidInvoice
+	default:
+		return nil
+	}
+}
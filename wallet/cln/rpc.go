@@ -0,0 +1,138 @@
+package cln
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// caller performs Core Lightning RPC calls.
+type caller interface {
+	call(ctx context.Context, method string, params, result interface{}) error
+}
+
+// rpcRequest is a JSON-RPC 2.0 request.
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response.
+type rpcResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// socketCaller sends JSON-RPC requests over the lightning-rpc unix socket of a node.
+// Each call uses its own connection, so calls can run concurrently.
+type socketCaller struct {
+	path   string
+	nextID uint64
+}
+
+func (c *socketCaller) call(ctx context.Context, method string, params, result interface{}) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.path)
+	if err != nil {
+		return fmt.Errorf("error connecting to lightning-rpc: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock reads and writes when the context is done.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now()) //nolint:errcheck
+	})
+	defer stop()
+
+	req := rpcRequest{
+		JSONRPC: "2.0",
+		ID:      atomic.AddUint64(&c.nextID, 1),
+		Method:  method,
+		Params:  params,
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return c.ctxErr(ctx, fmt.Errorf("error sending request: %w", err))
+	}
+
+	var resp rpcResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return c.ctxErr(ctx, fmt.Errorf("error reading response: %w", err))
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("unexpected response id %d for request %d", resp.ID, req.ID)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("error unmarshaling %s result: %w", method, err)
+	}
+	return nil
+}
+
+// ctxErr prefers the context error over the I/O error it caused.
+func (c *socketCaller) ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// restCaller calls the clnrest plugin, authenticating with a rune.
+type restCaller struct {
+	baseURL    string
+	authRune   string
+	httpClient *http.Client
+}
+
+func (c *restCaller) call(ctx context.Context, method string, params, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("error marshaling request body: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(c.baseURL, "/"), method)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Rune", c.authRune)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// clnrest reports RPC errors as the JSON-RPC error object.
+		var rpcErr RPCError
+		if err := json.Unmarshal(responseBody, &rpcErr); err == nil && rpcErr.Message != "" {
+			return &rpcErr
+		}
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, responseBody)
+	}
+
+	if err := json.Unmarshal(responseBody, result); err != nil {
+		return fmt.Errorf("error unmarshaling %s result: %w", method, err)
+	}
+	return nil
+}
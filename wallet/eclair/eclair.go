@@ -0,0 +1,156 @@
+package eclair
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// ErrPaymentFailed is returned when Eclair reports the payment as failed.
+var ErrPaymentFailed = errors.New("payment failed")
+
+// Types of the payment events returned by a blocking payinvoice call.
+const (
+	typePaymentSent   = "payment-sent"
+	typePaymentFailed = "payment-failed"
+)
+
+type eclairTimestamp struct {
+	ISO  string `json:"iso"`
+	Unix int64  `json:"unix"`
+}
+
+type eclairPaymentPart struct {
+	ID        string          `json:"id"`
+	Amount    int64           `json:"amount"`
+	FeesPaid  int64           `json:"feesPaid"`
+	Timestamp eclairTimestamp `json:"timestamp"`
+}
+
+type eclairPaymentResponse struct {
+	Type            string              `json:"type"`
+	ID              string              `json:"id"`
+	PaymentHash     string              `json:"paymentHash"`
+	PaymentPreimage string              `json:"paymentPreimage"`
+	RecipientAmount int64               `json:"recipientAmount"`
+	Parts           []eclairPaymentPart `json:"parts"`
+	Failures        []struct {
+		FailureMessage string `json:"failureMessage"`
+	} `json:"failures"`
+}
+
+type eclairError struct {
+	Error string `json:"error"`
+}
+
+// EclairWallet implements the Wallet interface using the Eclair HTTP API.
+type EclairWallet struct {
+	// BaseURL is the base URL of the Eclair API, e.g. http://localhost:8080.
+	BaseURL string
+	// password is the API password configured with eclair.api.password.
+	password string
+}
+
+// NewEclairWallet creates a new instance of EclairWallet.
+func NewEclairWallet(baseURL, password string) *EclairWallet {
+	return &EclairWallet{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		password: password,
+	}
+}
+
+// PayInvoice pays the given invoice and blocks until the payment settles or fails.
+func (ew *EclairWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	form := url.Values{}
+	form.Set("invoice", string(invoice))
+	form.Set("blocking", "true")
+
+	createdAt := time.Now()
+	responseBody, err := ew.makeRequest(ctx, "/payinvoice", form)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment eclairPaymentResponse
+	if err := json.Unmarshal(responseBody, &payment); err != nil {
+		return nil, fmt.Errorf("error unmarshaling Eclair response: %w", err)
+	}
+
+	switch payment.Type {
+	case typePaymentSent:
+		result := &wallet.PaymentResult{
+			Preimage:  payment.PaymentPreimage,
+			Success:   true,
+			Amount:    lnwire.MilliSatoshi(payment.RecipientAmount),
+			CreatedAt: createdAt,
+			PaymentID: payment.ID,
+		}
+		// A payment may be split into several parts, each paying its own fee.
+		// It settled when its last part did.
+		for _, part := range payment.Parts {
+			result.Fee += lnwire.MilliSatoshi(part.FeesPaid)
+			if settledAt := time.Unix(part.Timestamp.Unix, 0); settledAt.After(result.SettledAt) {
+				result.SettledAt = settledAt
+			}
+		}
+		if len(payment.Parts) == 0 {
+			result.SettledAt = time.Now()
+		}
+		if hash, err := lntypes.MakeHashFromStr(payment.PaymentHash); err == nil {
+			result.PaymentHash = hash
+		}
+		return result, nil
+	case typePaymentFailed:
+		if n := len(payment.Failures); n > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrPaymentFailed, payment.Failures[n-1].FailureMessage)
+		}
+		return nil, ErrPaymentFailed
+	default:
+		return nil, fmt.Errorf("unexpected Eclair payment event %q", payment.Type)
+	}
+}
+
+func (ew *EclairWallet) makeRequest(ctx context.Context, path string, form url.Values) ([]byte, error) {
+	url := fmt.Sprintf("%s%s", ew.BaseURL, path)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	// Eclair uses basic auth with an empty user name.
+	req.SetBasicAuth("", ew.password)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr eclairError
+		if err := json.Unmarshal(responseBody, &apiErr); err == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, apiErr.Error)
+		}
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, responseBody)
+	}
+
+	return responseBody, nil
+}
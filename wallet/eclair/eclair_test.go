@@ -0,0 +1,127 @@
+package eclair
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+const (
+	testPassword    = "eclairpw"
+	testPaymentHash = "f3a2d4c1f1e2d3c4b5a6978877665544332211ffeeddccbbaa99887766554433"
+	testPreimage    = "0102030405060708091011121314151617181920212223242526272829303132"
+)
+
+// MockEclairServer mimics the payinvoice endpoint of the Eclair API.
+type MockEclairServer struct {
+	server *httptest.Server
+}
+
+func NewMockEclairServer() *MockEclairServer {
+	mock := &MockEclairServer{}
+	mock.server = httptest.NewServer(http.HandlerFunc(mock.handler))
+	return mock
+}
+
+func (m *MockEclairServer) handler(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || user != "" || password != testPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`"The supplied authentication is invalid"`)) //nolint:errcheck
+		return
+	}
+
+	if r.URL.Path != "/payinvoice" || r.Method != "POST" {
+		http.Error(w, `{"error": "Invalid request"}`, http.StatusNotFound)
+		return
+	}
+	if r.PostFormValue("blocking") != "true" {
+		// Without blocking Eclair only returns the payment id.
+		w.Write([]byte(`"e5a9b1b2-4c3d-4d5e-8f90-0123456789ab"`)) //nolint:errcheck
+		return
+	}
+
+	switch r.PostFormValue("invoice") {
+	case "lnbc1invoice":
+		w.Write([]byte(`{
+			"type": "payment-sent",
+			"id": "e5a9b1b2-4c3d-4d5e-8f90-0123456789ab",
+			"paymentHash": "` + testPaymentHash + `",
+			"paymentPreimage": "` + testPreimage + `",
+			"recipientAmount": 1000000,
+			"recipientNodeId": "03933884aaf1d6b108397e5efe5c86bcf2d8ca8d2f700eda99db9214fc2712b134",
+			"parts": [
+				{"id": "b8b3d1c2", "amount": 600000, "feesPaid": 1000, "toChannelId": "00", "timestamp": {"iso": "2024-05-01T12:00:05Z", "unix": 1714564805}},
+				{"id": "c1d2e3f4", "amount": 400000, "feesPaid": 500, "toChannelId": "01", "timestamp": {"iso": "2024-05-01T12:00:00Z", "unix": 1714564800}}
+			]
+		}`)) //nolint:errcheck
+	case "lnbc1noroute":
+		w.Write([]byte(`{
+			"type": "payment-failed",
+			"id": "e5a9b1b2-4c3d-4d5e-8f90-0123456789ab",
+			"paymentHash": "` + testPaymentHash + `",
+			"failures": [{"amount": 1000000, "route": [], "t": "route not found", "failureMessage": "route not found"}],
+			"timestamp": {"iso": "2024-05-01T12:00:00Z", "unix": 1714564800}
+		}`)) //nolint:errcheck
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid payment request"}`)) //nolint:errcheck
+	}
+}
+
+func (m *MockEclairServer) Close() {
+	m.server.Close()
+}
+
+func (m *MockEclairServer) URL() string {
+	return m.server.URL
+}
+
+func TestPayInvoice(t *testing.T) {
+	s := NewMockEclairServer()
+	defer s.Close()
+
+	w := NewEclairWallet(s.URL(), testPassword)
+	result, err := w.PayInvoice(context.Background(), "lnbc1invoice")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, testPreimage, result.Preimage)
+	require.Equal(t, testPaymentHash, result.PaymentHash.String())
+	require.Equal(t, lnwire.MilliSatoshi(1000000), result.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(1500), result.Fee)
+	require.Equal(t, time.Unix(1714564805, 0), result.SettledAt)
+	require.Equal(t, "e5a9b1b2-4c3d-4d5e-8f90-0123456789ab", result.PaymentID)
+}
+
+func TestPayInvoiceErrors(t *testing.T) {
+	s := NewMockEclairServer()
+	defer s.Close()
+
+	tests := []struct {
+		name      string
+		password  string
+		invoice   string
+		wantError error
+	}{
+		{"Invalid password", "wrong", "lnbc1invoice", nil},
+		{"Payment failed", testPassword, "lnbc1noroute", ErrPaymentFailed},
+		{"Invalid invoice", testPassword, "garbage", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewEclairWallet(s.URL(), tt.password)
+			_, err := w.PayInvoice(context.Background(), wallet.Invoice(tt.invoice))
+			require.Error(t, err)
+			if tt.wantError != nil {
+				require.ErrorIs(t, err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package wallet
+
+import (
+	"context"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// InvoiceState describes where a created invoice is in its lifecycle.
+type InvoiceState int
+
+const (
+	// InvoiceStateOpen means the invoice was created and has not been paid yet.
+	InvoiceStateOpen InvoiceState = iota
+
+	// InvoiceStateAccepted means a payment is held but not settled yet.
+	InvoiceStateAccepted
+
+	// InvoiceStateSettled means the invoice has been paid.
+	InvoiceStateSettled
+
+	// InvoiceStateCanceled means the invoice was canceled or expired and can no longer be paid.
+	InvoiceStateCanceled
+)
+
+// String returns a human readable name for the state.
+func (s InvoiceState) String() string {
+	switch s {
+	case InvoiceStateOpen:
+		return "open"
+	case InvoiceStateAccepted:
+		return "accepted"
+	case InvoiceStateSettled:
+		return "settled"
+	case InvoiceStateCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// Final reports whether the state can no longer change.
+func (s InvoiceState) Final() bool {
+	return s == InvoiceStateSettled || s == InvoiceStateCanceled
+}
+
+// InvoiceRequest holds the parameters of an invoice to create.
+type InvoiceRequest struct {
+	// Amount is the amount to request.
+	Amount lnwire.MilliSatoshi
+
+	// Memo is the description included in the invoice.
+	Memo string
+
+	// Expiry is how long the invoice stays payable. Zero uses the backend default.
+	Expiry time.Duration
+}
+
+// InvoiceStatus describes an invoice created by an Invoicer.
+type InvoiceStatus struct {
+	// PaymentRequest is the BOLT11 payment request.
+	PaymentRequest Invoice
+
+	// PaymentHash is the payment hash of the invoice.
+	PaymentHash lntypes.Hash
+
+	// State is the current state of the invoice.
+	State InvoiceState
+
+	// Preimage is the hex encoded preimage, if known. It is always set once the invoice is settled.
+	Preimage string
+
+	// AmountPaid is the amount received, set once the invoice is settled.
+	AmountPaid lnwire.MilliSatoshi
+
+	// SettledAt is the time the invoice was settled, if it is.
+	SettledAt time.Time
+}
+
+// Invoicer defines the interface for wallet implementations capable of receiving payments.
+// It is the counterpart of Wallet needed to serve L402 challenges.
+type Invoicer interface {
+	// CreateInvoice creates a new invoice.
+	CreateInvoice(ctx context.Context, req InvoiceRequest) (*InvoiceStatus, error)
+
+	// LookupInvoice returns the current status of the invoice with the given payment hash.
+	LookupInvoice(ctx context.Context, hash lntypes.Hash) (*InvoiceStatus, error)
+
+	// SubscribeInvoice streams status updates for the invoice with the given payment hash.
+	// The updates channel is closed once the invoice reaches a final state or ctx is done.
+	// Errors that end the subscription early are sent on the error channel.
+	SubscribeInvoice(ctx context.Context, hash lntypes.Hash) (<-chan InvoiceStatus, <-chan error, error)
+}
@@ -0,0 +1,218 @@
+package lnbits
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// ErrPaymentFailed is returned when LNbits reports the payment as failed.
+var ErrPaymentFailed = errors.New("payment failed")
+
+// ErrPaymentPending is returned when a payment has not settled within the payment timeout.
+// The payment may still settle later. It is wallet.ErrPaymentPending.
+var ErrPaymentPending = wallet.ErrPaymentPending
+
+// DefaultPaymentTimeout is how long PayInvoice waits for a payment to settle by default.
+const DefaultPaymentTimeout = 60 * time.Second
+
+// statusFailed is the state reported in the details of a failed payment.
+const statusFailed = "failed"
+
+// statusPaymentError is the HTTP status LNbits responds with when a payment attempt fails.
+const statusPaymentError = 520
+
+// emptyPreimage is reported by LNbits until the preimage of a payment is known.
+const emptyPreimage = "0000000000000000000000000000000000000000000000000000000000000000"
+
+type lnbitsPayResponse struct {
+	PaymentHash string `json:"payment_hash"`
+	CheckingID  string `json:"checking_id"`
+}
+
+type lnbitsPaymentStatus struct {
+	Paid     bool   `json:"paid"`
+	Preimage string `json:"preimage"`
+	Details  struct {
+		Status string `json:"status"`
+		Amount int64  `json:"amount"`
+		Fee    int64  `json:"fee"`
+	} `json:"details"`
+}
+
+type lnbitsError struct {
+	Detail string `json:"detail"`
+}
+
+// LnbitsWallet implements the Wallet interface using the LNbits REST API.
+type LnbitsWallet struct {
+	// BaseURL is the base URL of the LNbits instance.
+	BaseURL string
+	// PollInterval is how often PayInvoice checks the payment status.
+	// If zero, it defaults to 1 second.
+	PollInterval time.Duration
+	// PaymentTimeout is how long PayInvoice waits for a payment to settle.
+	// If zero, it defaults to DefaultPaymentTimeout.
+	PaymentTimeout time.Duration
+	// adminKey is the admin key of the wallet, required for outgoing payments.
+	adminKey string
+}
+
+// NewLnbitsWallet creates a new instance of LnbitsWallet for the wallet with the given admin key.
+func NewLnbitsWallet(baseURL, adminKey string) *LnbitsWallet {
+	return &LnbitsWallet{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		adminKey: adminKey,
+	}
+}
+
+// PayInvoice pays the given invoice and waits until its preimage is available.
+// LNbits may accept a payment before it settles, so the payment status is polled
+// until the payment succeeds, fails, the payment timeout passes or ctx is done.
+func (lw *LnbitsWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	body := map[string]interface{}{
+		"out":    true,
+		"bolt11": invoice,
+	}
+
+	createdAt := time.Now()
+	responseBody, err := lw.makeRequest(ctx, "POST", "/api/v1/payments", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var payResponse lnbitsPayResponse
+	if err := json.Unmarshal(responseBody, &payResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshaling LNbits response: %w", err)
+	}
+	if payResponse.PaymentHash == "" {
+		return nil, fmt.Errorf("LNbits response is missing the payment hash")
+	}
+
+	interval := lw.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	timeout := lw.PaymentTimeout
+	if timeout <= 0 {
+		timeout = DefaultPaymentTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		status, err := lw.paymentStatus(ctx, payResponse.PaymentHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if status.Details.Status == statusFailed {
+			return nil, ErrPaymentFailed
+		}
+		if status.Paid && status.Preimage != "" && status.Preimage != emptyPreimage {
+			// Outgoing payments are reported with negative amounts in millisatoshis.
+			result := &wallet.PaymentResult{
+				Preimage:  status.Preimage,
+				Success:   true,
+				Amount:    lnwire.MilliSatoshi(abs(status.Details.Amount)),
+				Fee:       lnwire.MilliSatoshi(abs(status.Details.Fee)),
+				CreatedAt: createdAt,
+				SettledAt: time.Now(),
+				PaymentID: payResponse.CheckingID,
+			}
+			if hash, err := lntypes.MakeHashFromStr(payResponse.PaymentHash); err == nil {
+				result.PaymentHash = hash
+			}
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return nil, fmt.Errorf("%w after %s", ErrPaymentPending, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// paymentStatus looks up the status of the payment with the given hash.
+func (lw *LnbitsWallet) paymentStatus(ctx context.Context, paymentHash string) (*lnbitsPaymentStatus, error) {
+	responseBody, err := lw.makeRequest(ctx, "GET", "/api/v1/payments/"+paymentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var status lnbitsPaymentStatus
+	if err := json.Unmarshal(responseBody, &status); err != nil {
+		return nil, fmt.Errorf("error unmarshaling LNbits payment status: %w", err)
+	}
+	return &status, nil
+}
+
+// abs returns the absolute value of v.
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (lw *LnbitsWallet) makeRequest(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	url := fmt.Sprintf("%s%s", lw.BaseURL, path)
+
+	var requestBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request body: %w", err)
+		}
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", lw.adminKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr lnbitsError
+		if err := json.Unmarshal(responseBody, &apiErr); err == nil && apiErr.Detail != "" {
+			if resp.StatusCode == statusPaymentError {
+				return nil, fmt.Errorf("%w: %s", ErrPaymentFailed, apiErr.Detail)
+			}
+			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, apiErr.Detail)
+		}
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, responseBody)
+	}
+
+	return responseBody, nil
+}
@@ -0,0 +1,156 @@
+package lnbits
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+const (
+	testAdminKey    = "adminkey123"
+	testPaymentHash = "f3a2d4c1f1e2d3c4b5a6978877665544332211ffeeddccbbaa99887766554433"
+	testPreimage    = "0102030405060708091011121314151617181920212223242526272829303132"
+)
+
+// mockLnbitsServer mimics the payment endpoints of the LNbits API.
+// The payment settles once its status has been checked settleAfter times.
+type mockLnbitsServer struct {
+	mu          sync.Mutex
+	checks      int
+	settleAfter int
+	fail        bool
+}
+
+func (m *mockLnbitsServer) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Api-Key") != testAdminKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"detail": "Invalid key"}`)) //nolint:errcheck
+		return
+	}
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/api/v1/payments":
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["out"] != true {
+			http.Error(w, `{"detail": "Invalid request"}`, http.StatusBadRequest)
+			return
+		}
+		if body["bolt11"] == "expired" {
+			w.WriteHeader(statusPaymentError)
+			w.Write([]byte(`{"detail": "Invoice expired"}`)) //nolint:errcheck
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"payment_hash": testPaymentHash,
+			"checking_id":  testPaymentHash,
+		})
+
+	case r.Method == "GET" && r.URL.Path == "/api/v1/payments/"+testPaymentHash:
+		m.mu.Lock()
+		m.checks++
+		settled := m.checks >= m.settleAfter
+		m.mu.Unlock()
+
+		status := map[string]interface{}{
+			"paid":     false,
+			"preimage": emptyPreimage,
+			"details":  map[string]interface{}{"status": "pending", "amount": -1000000, "fee": -1000},
+		}
+		switch {
+		case m.fail:
+			status["details"] = map[string]interface{}{"status": "failed"}
+		case settled:
+			status["paid"] = true
+			status["preimage"] = testPreimage
+			status["details"] = map[string]interface{}{"status": "success", "amount": -1000000, "fee": -1000}
+		}
+		json.NewEncoder(w).Encode(status) //nolint:errcheck
+
+	default:
+		http.Error(w, `{"detail": "Not found"}`, http.StatusNotFound)
+	}
+}
+
+func newTestWallet(t *testing.T, m *mockLnbitsServer, adminKey string) *LnbitsWallet {
+	t.Helper()
+
+	s := httptest.NewServer(http.HandlerFunc(m.handler))
+	t.Cleanup(s.Close)
+
+	w := NewLnbitsWallet(s.URL+"/", adminKey)
+	w.PollInterval = 10 * time.Millisecond
+	return w
+}
+
+func TestPayInvoice(t *testing.T) {
+	m := &mockLnbitsServer{settleAfter: 3}
+	w := newTestWallet(t, m, testAdminKey)
+
+	result, err := w.PayInvoice(context.Background(), "lnbc1invoice")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, testPreimage, result.Preimage)
+	require.Equal(t, testPaymentHash, result.PaymentHash.String())
+	require.Equal(t, lnwire.MilliSatoshi(1000000), result.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(1000), result.Fee)
+	require.Equal(t, testPaymentHash, result.PaymentID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	require.Equal(t, 3, m.checks)
+}
+
+func TestPayInvoiceErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		server    *mockLnbitsServer
+		adminKey  string
+		invoice   string
+		wantError error
+	}{
+		{"Invalid key", &mockLnbitsServer{}, "invoicekey", "lnbc1invoice", nil},
+		{"Payment rejected", &mockLnbitsServer{}, testAdminKey, "expired", ErrPaymentFailed},
+		{"Payment failed", &mockLnbitsServer{fail: true}, testAdminKey, "lnbc1invoice", ErrPaymentFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWallet(t, tt.server, tt.adminKey)
+
+			_, err := w.PayInvoice(context.Background(), wallet.Invoice(tt.invoice))
+			require.Error(t, err)
+			if tt.wantError != nil {
+				require.ErrorIs(t, err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestPayInvoiceContextCanceled(t *testing.T) {
+	// The payment never settles, so PayInvoice returns once the context is done.
+	m := &mockLnbitsServer{settleAfter: 1 << 30}
+	w := newTestWallet(t, m, testAdminKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := w.PayInvoice(ctx, "lnbc1invoice")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPayInvoiceTimeout(t *testing.T) {
+	m := &mockLnbitsServer{settleAfter: 1 << 30}
+	w := newTestWallet(t, m, testAdminKey)
+	w.PaymentTimeout = 50 * time.Millisecond
+
+	_, err := w.PayInvoice(context.Background(), "lnbc1invoice")
+	require.ErrorIs(t, err, ErrPaymentPending)
+}
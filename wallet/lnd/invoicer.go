@@ -0,0 +1,159 @@
+package lnd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lightninglabs/lndclient"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// LndInvoicer implements the Invoicer interface using an LND node.
+type LndInvoicer struct {
+	lightning lndclient.LightningClient
+	invoices  lndclient.InvoicesClient
+}
+
+// NewLndInvoicer creates a new instance of LndInvoicer.
+func NewLndInvoicer(lightning lndclient.LightningClient, invoices lndclient.InvoicesClient) *LndInvoicer {
+	return &LndInvoicer{
+		lightning: lightning,
+		invoices:  invoices,
+	}
+}
+
+// NewLndInvoicerFromConfig creates a new LndInvoicer instance using the provided configuration.
+func NewLndInvoicerFromConfig(cfg *LndWalletConfig) (*LndInvoicer, error) {
+	lndCfg := &lndclient.LndServicesConfig{
+		LndAddress:  cfg.GrpcAddress,
+		Network:     lndclient.Network(cfg.Network),
+		MacaroonDir: cfg.MacaroonPath,
+		TLSPath:     cfg.TLSPath,
+	}
+
+	client, err := lndclient.NewLndServices(lndCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LndInvoicer{
+		lightning: client.Client,
+		invoices:  client.Invoices,
+	}, nil
+}
+
+// CreateInvoice adds a new invoice to the LND node.
+func (li *LndInvoicer) CreateInvoice(ctx context.Context, req wallet.InvoiceRequest) (*wallet.InvoiceStatus, error) {
+	hash, paymentRequest, err := li.lightning.AddInvoice(ctx, &invoicesrpc.AddInvoiceData{
+		Memo:   req.Memo,
+		Value:  req.Amount,
+		Expiry: int64(req.Expiry.Seconds()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &wallet.InvoiceStatus{
+		PaymentRequest: wallet.Invoice(paymentRequest),
+		PaymentHash:    hash,
+		State:          wallet.InvoiceStateOpen,
+	}, nil
+}
+
+// LookupInvoice returns the status of the invoice with the given payment hash.
+func (li *LndInvoicer) LookupInvoice(ctx context.Context, hash lntypes.Hash) (*wallet.InvoiceStatus, error) {
+	invoice, err := li.lightning.LookupInvoice(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &wallet.InvoiceStatus{
+		PaymentRequest: wallet.Invoice(invoice.PaymentRequest),
+		PaymentHash:    invoice.Hash,
+		State:          invoiceState(invoice.State),
+		AmountPaid:     invoice.AmountPaid,
+	}
+	if invoice.Preimage != nil {
+		status.Preimage = invoice.Preimage.String()
+	}
+	if status.State == wallet.InvoiceStateSettled {
+		status.SettledAt = invoice.SettleDate
+	}
+
+	return status, nil
+}
+
+// SubscribeInvoice streams status updates for the invoice with the given payment hash.
+// Settled updates are completed with a lookup, since LND's invoice stream does not carry the preimage.
+func (li *LndInvoicer) SubscribeInvoice(ctx context.Context, hash lntypes.Hash) (<-chan wallet.InvoiceStatus, <-chan error, error) {
+	updateChan, errChan, err := li.invoices.SubscribeSingleInvoice(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := make(chan wallet.InvoiceStatus)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(updates)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case update, ok := <-updateChan:
+				if !ok {
+					errs <- fmt.Errorf("invoice update channel closed")
+					return
+				}
+
+				status := wallet.InvoiceStatus{
+					PaymentHash: hash,
+					State:       invoiceState(update.State),
+				}
+				if status.State == wallet.InvoiceStateSettled {
+					settled, err := li.LookupInvoice(ctx, hash)
+					if err != nil {
+						errs <- err
+						return
+					}
+					status = *settled
+				}
+
+				select {
+				case updates <- status:
+				case <-ctx.Done():
+					return
+				}
+
+				if status.State.Final() {
+					return
+				}
+
+			case err := <-errChan:
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	return updates, errs, nil
+}
+
+// invoiceState maps an LND contract state to an invoice state.
+func invoiceState(state channeldb.ContractState) wallet.InvoiceState {
+	switch state {
+	case channeldb.ContractSettled:
+		return wallet.InvoiceStateSettled
+	case channeldb.ContractCanceled:
+		return wallet.InvoiceStateCanceled
+	case channeldb.ContractAccepted:
+		return wallet.InvoiceStateAccepted
+	default:
+		return wallet.InvoiceStateOpen
+	}
+}
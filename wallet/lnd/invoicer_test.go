@@ -0,0 +1,133 @@
+package lnd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightninglabs/lndclient"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+var (
+	testPreimage = lntypes.Preimage{1, 2, 3}
+	testHash     = testPreimage.Hash()
+)
+
+type mockLightningClient struct {
+	lndclient.LightningClient
+
+	added    *invoicesrpc.AddInvoiceData
+	invoice  *lndclient.Invoice
+	payments []lndclient.Payment
+	err      error
+}
+
+func (m *mockLightningClient) AddInvoice(ctx context.Context, in *invoicesrpc.AddInvoiceData) (lntypes.Hash, string, error) {
+	if m.err != nil {
+		return lntypes.Hash{}, "", m.err
+	}
+	m.added = in
+	return testHash, "lnbcrt1test", nil
+}
+
+func (m *mockLightningClient) LookupInvoice(ctx context.Context, hash lntypes.Hash) (*lndclient.Invoice, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.invoice, nil
+}
+
+type mockInvoicesClient struct {
+	lndclient.InvoicesClient
+
+	updates []lndclient.InvoiceUpdate
+}
+
+func (m *mockInvoicesClient) SubscribeSingleInvoice(ctx context.Context, hash lntypes.Hash) (<-chan lndclient.InvoiceUpdate, <-chan error, error) {
+	updateChan := make(chan lndclient.InvoiceUpdate, len(m.updates))
+	for _, update := range m.updates {
+		updateChan <- update
+	}
+	return updateChan, make(chan error), nil
+}
+
+func TestLndInvoicer_CreateInvoice(t *testing.T) {
+	lightning := &mockLightningClient{}
+	invoicer := NewLndInvoicer(lightning, &mockInvoicesClient{})
+
+	status, err := invoicer.CreateInvoice(context.Background(), wallet.InvoiceRequest{
+		Amount: 21000,
+		Memo:   "test",
+		Expiry: 10 * time.Minute,
+	})
+	require.NoError(t, err)
+	require.Equal(t, wallet.Invoice("lnbcrt1test"), status.PaymentRequest)
+	require.Equal(t, testHash, status.PaymentHash)
+	require.Equal(t, wallet.InvoiceStateOpen, status.State)
+
+	require.Equal(t, lnwire.MilliSatoshi(21000), lightning.added.Value)
+	require.Equal(t, "test", lightning.added.Memo)
+	require.Equal(t, int64(600), lightning.added.Expiry)
+
+	lightning.err = errors.New("node offline")
+	_, err = invoicer.CreateInvoice(context.Background(), wallet.InvoiceRequest{Amount: 21000})
+	require.Error(t, err)
+}
+
+func TestLndInvoicer_LookupInvoice(t *testing.T) {
+	settleDate := time.Now()
+	lightning := &mockLightningClient{invoice: &lndclient.Invoice{
+		Preimage:       &testPreimage,
+		Hash:           testHash,
+		PaymentRequest: "lnbcrt1test",
+		AmountPaid:     21000,
+		SettleDate:     settleDate,
+		State:          channeldb.ContractSettled,
+	}}
+	invoicer := NewLndInvoicer(lightning, &mockInvoicesClient{})
+
+	status, err := invoicer.LookupInvoice(context.Background(), testHash)
+	require.NoError(t, err)
+	require.Equal(t, wallet.InvoiceStateSettled, status.State)
+	require.Equal(t, testPreimage.String(), status.Preimage)
+	require.Equal(t, lnwire.MilliSatoshi(21000), status.AmountPaid)
+	require.Equal(t, settleDate, status.SettledAt)
+}
+
+func TestLndInvoicer_SubscribeInvoice(t *testing.T) {
+	lightning := &mockLightningClient{invoice: &lndclient.Invoice{
+		Preimage: &testPreimage,
+		Hash:     testHash,
+		State:    channeldb.ContractSettled,
+	}}
+	invoices := &mockInvoicesClient{updates: []lndclient.InvoiceUpdate{
+		{State: channeldb.ContractOpen},
+		{State: channeldb.ContractAccepted},
+		{State: channeldb.ContractSettled, AmtPaid: btcutil.Amount(21)},
+	}}
+	invoicer := NewLndInvoicer(lightning, invoices)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updates, _, err := invoicer.SubscribeInvoice(ctx, testHash)
+	require.NoError(t, err)
+
+	var got []wallet.InvoiceStatus
+	for update := range updates {
+		got = append(got, update)
+	}
+	require.Len(t, got, 3)
+	require.Equal(t, wallet.InvoiceStateOpen, got[0].State)
+	require.Equal(t, wallet.InvoiceStateAccepted, got[1].State)
+	require.Equal(t, wallet.InvoiceStateSettled, got[2].State)
+	require.Equal(t, testPreimage.String(), got[2].Preimage)
+}
@@ -3,6 +3,7 @@ package lnd
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/lightninglabs/lndclient"
@@ -10,9 +11,14 @@ import (
 	"github.com/sulusolutions/gol402/wallet"
 )
 
+// recentPayments is how many of the latest payments are searched for the details of a payment.
+const recentPayments = 10
+
 // LndWallet implements the Wallet interface using an LND node.
 type LndWallet struct {
 	client lndclient.RouterClient
+	// lightning is used to look up the route of settled payments. It is optional.
+	lightning lndclient.LightningClient
 }
 
 // NewLndWallet creates a new instance of LndWallet.
@@ -45,7 +51,8 @@ func NewLndWalletFromConfig(cfg *LndWalletConfig) (*LndWallet, error) {
 	}
 
 	return &LndWallet{
-		client: client.Router,
+		client:    client.Router,
+		lightning: client.Client,
 	}, nil
 }
 
@@ -58,6 +65,7 @@ func (lw *LndWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*w
 	}
 
 	// Send the payment request to LND
+	createdAt := time.Now()
 	statusChan, errChan, err := lw.client.SendPayment(ctx, payReq)
 	if err != nil {
 		return nil, err
@@ -74,11 +82,17 @@ func (lw *LndWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*w
 			}
 
 			if paymentStatus.State == lnrpc.Payment_SUCCEEDED {
-				preimage := paymentStatus.Preimage.String()
-				return &wallet.PaymentResult{
-					Preimage: preimage,
-					Success:  true,
-				}, nil
+				result := &wallet.PaymentResult{
+					Preimage:    paymentStatus.Preimage.String(),
+					Success:     true,
+					PaymentHash: paymentStatus.Preimage.Hash(),
+					Amount:      paymentStatus.Value,
+					Fee:         paymentStatus.Fee,
+					CreatedAt:   createdAt,
+					SettledAt:   time.Now(),
+				}
+				lw.addRouteDetails(ctx, result)
+				return result, nil
 			} else if paymentStatus.State == lnrpc.Payment_FAILED {
 				return nil, fmt.Errorf("payment failed: %v", paymentStatus.State)
 			}
@@ -88,3 +102,34 @@ func (lw *LndWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*w
 		}
 	}
 }
+
+// addRouteDetails adds the hop count and payment index of the settled payment to result.
+// The payment status stream does not report routes, so the payment is looked up among the
+// latest payments. Failing to find it leaves result unchanged.
+func (lw *LndWallet) addRouteDetails(ctx context.Context, result *wallet.PaymentResult) {
+	if lw.lightning == nil {
+		return
+	}
+
+	resp, err := lw.lightning.ListPayments(ctx, lndclient.ListPaymentsRequest{
+		MaxPayments: recentPayments,
+		Reversed:    true,
+	})
+	if err != nil {
+		return
+	}
+
+	for _, payment := range resp.Payments {
+		if payment.Hash != result.PaymentHash {
+			continue
+		}
+
+		result.PaymentID = strconv.FormatUint(payment.SequenceNumber, 10)
+		for _, htlc := range payment.Htlcs {
+			if htlc.Status == lnrpc.HTLCAttempt_SUCCEEDED && htlc.Route != nil && len(htlc.Route.Hops) > result.Hops {
+				result.Hops = len(htlc.Route.Hops)
+			}
+		}
+		return
+	}
+}
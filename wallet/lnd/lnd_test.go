@@ -5,10 +5,12 @@ import (
 	"crypto/rand"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/lightninglabs/lndclient"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/stretchr/testify/require"
 	"github.com/sulusolutions/gol402/wallet"
 )
@@ -19,6 +21,8 @@ type mockRouterClient struct {
 	completePaymentOp bool                        // Result is relayed through status channel only if this is true
 	paymentStatus     lnrpc.Payment_PaymentStatus // Status to be returned by SendPayment through status channel.
 	mockError         error                       // Add field to simulate an error from SendPayment
+	preimage          lntypes.Preimage            // Preimage of the payment, random if zero
+	value, fee        lnwire.MilliSatoshi         // Amount and fee reported for the payment
 }
 
 func (m *mockRouterClient) SendPayment(ctx context.Context, req lndclient.SendPaymentRequest) (chan lndclient.PaymentStatus, chan error, error) {
@@ -31,13 +35,17 @@ func (m *mockRouterClient) SendPayment(ctx context.Context, req lndclient.SendPa
 
 	go func() {
 		if m.completePaymentOp {
-			var preimage lntypes.Preimage
-			if _, err := rand.Read(preimage[:]); err != nil {
-				errChan <- err // Handle error appropriately
+			preimage := m.preimage
+			if preimage == (lntypes.Preimage{}) {
+				if _, err := rand.Read(preimage[:]); err != nil {
+					errChan <- err // Handle error appropriately
+				}
 			}
 			statusChan <- lndclient.PaymentStatus{
 				State:    m.paymentStatus,
-				Preimage: preimage, // Replace with a valid preimage if needed
+				Preimage: preimage,
+				Value:    m.value,
+				Fee:      m.fee,
 			}
 		} else {
 			errChan <- errors.New("mock payment failure")
@@ -138,3 +146,58 @@ func TestLndWallet_PayInvoice(t *testing.T) {
 		})
 	}
 }
+
+func (m *mockLightningClient) ListPayments(ctx context.Context, req lndclient.ListPaymentsRequest) (*lndclient.ListPaymentsResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &lndclient.ListPaymentsResponse{Payments: m.payments}, nil
+}
+
+// TestLndWallet_PayInvoiceDetails verifies that the payment result carries the payment details
+// and the route of the settled payment.
+func TestLndWallet_PayInvoiceDetails(t *testing.T) {
+	router := &mockRouterClient{
+		completePaymentOp: true,
+		paymentStatus:     lnrpc.Payment_SUCCEEDED,
+		preimage:          testPreimage,
+		value:             100000,
+		fee:               25,
+	}
+	lightning := &mockLightningClient{
+		payments: []lndclient.Payment{
+			{Hash: lntypes.Hash{9}, SequenceNumber: 6},
+			{
+				Hash:           testHash,
+				SequenceNumber: 5,
+				Htlcs: []*lnrpc.HTLCAttempt{
+					{Status: lnrpc.HTLCAttempt_FAILED, Route: &lnrpc.Route{Hops: make([]*lnrpc.Hop, 5)}},
+					{Status: lnrpc.HTLCAttempt_SUCCEEDED, Route: &lnrpc.Route{Hops: make([]*lnrpc.Hop, 3)}},
+				},
+			},
+		},
+	}
+	lndWallet := &LndWallet{client: router, lightning: lightning}
+
+	before := time.Now()
+	result, err := lndWallet.PayInvoice(context.Background(), "mock_invoice")
+	require.NoError(t, err)
+
+	require.Equal(t, testPreimage.String(), result.Preimage)
+	require.Equal(t, testHash, result.PaymentHash)
+	require.Equal(t, lnwire.MilliSatoshi(100000), result.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(25), result.Fee)
+	require.Equal(t, lnwire.MilliSatoshi(100025), result.Total())
+	require.Equal(t, 3, result.Hops)
+	require.Equal(t, "5", result.PaymentID)
+	require.False(t, result.CreatedAt.Before(before))
+	require.False(t, result.SettledAt.Before(result.CreatedAt))
+
+	// Without the payment lookup the route details are unknown.
+	lightning.err = errors.New("lookup failed")
+	result, err = lndWallet.PayInvoice(context.Background(), "mock_invoice")
+	require.NoError(t, err)
+	require.Equal(t, testHash, result.PaymentHash)
+	require.Zero(t, result.Hops)
+	require.Empty(t, result.PaymentID)
+}
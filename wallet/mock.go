@@ -2,12 +2,20 @@ package wallet
 
 import (
 	"context"
-)
+	"fmt"
+	"sync"
+	"time"
 
-const (
-	mockPreimage = "12345abcd"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/zpay32"
 )
 
+// MockPreimage is the preimage returned by MockWallet for successful payments.
+// Its SHA256 hash can be used as the payment hash of test invoices.
+const MockPreimage = "000000000000000000000000000000000000000000000000000000012345abcd"
+
 // MockWallet is a mock implementation of the Wallet interface for testing purposes.
 type MockWallet struct {
 	// Error to be returned by PayInvoice. If nil, PayInvoice will simulate a successful payment.
@@ -32,8 +40,192 @@ func (mw *MockWallet) PayInvoice(ctx context.Context, invoice Invoice) (*Payment
 		// Continue if the context is not done.
 	}
 
-	return &PaymentResult{
-		Preimage: mockPreimage,
-		Success:  mw.PaymentError == nil,
-	}, mw.PaymentError
+	if mw.PaymentError != nil {
+		return nil, mw.PaymentError
+	}
+
+	preimage, err := lntypes.MakePreimageFromStr(MockPreimage)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := &PaymentResult{
+		Preimage:    MockPreimage,
+		Success:     true,
+		PaymentHash: preimage.Hash(),
+		CreatedAt:   now,
+		SettledAt:   now,
+		Hops:        1,
+	}
+	if decoded, err := invoice.Decode(); err == nil {
+		result.Amount = decoded.Amount
+	}
+	return result, nil
+}
+
+// MockInvoicer is a mock implementation of the Invoicer interface for testing purposes.
+// It creates signed regtest invoices that all share the payment hash of MockPreimage,
+// so they can be paid by MockWallet.
+type MockInvoicer struct {
+	// Error to be returned by CreateInvoice. If nil, invoices are created successfully.
+	InvoiceError error
+
+	mu          sync.Mutex
+	key         *btcec.PrivateKey
+	created     int
+	invoices    map[lntypes.Hash]*InvoiceStatus
+	subscribers map[lntypes.Hash][]chan InvoiceStatus
+}
+
+// NewMockInvoicer creates a new instance of MockInvoicer with customizable behavior.
+func NewMockInvoicer(err error) *MockInvoicer {
+	return &MockInvoicer{
+		InvoiceError: err,
+		invoices:     make(map[lntypes.Hash]*InvoiceStatus),
+		subscribers:  make(map[lntypes.Hash][]chan InvoiceStatus),
+	}
+}
+
+// CreateInvoice creates a signed regtest invoice for the requested amount and memo.
+func (mi *MockInvoicer) CreateInvoice(ctx context.Context, req InvoiceRequest) (*InvoiceStatus, error) {
+	if mi.InvoiceError != nil {
+		return nil, mi.InvoiceError
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	if mi.key == nil {
+		key, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			return nil, err
+		}
+		mi.key = key
+	}
+
+	preimage, err := lntypes.MakePreimageFromStr(MockPreimage)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []func(*zpay32.Invoice){zpay32.Amount(req.Amount), zpay32.Description(req.Memo)}
+	if req.Expiry > 0 {
+		options = append(options, zpay32.Expiry(req.Expiry))
+	}
+	inv, err := zpay32.NewInvoice(&chaincfg.RegressionNetParams, preimage.Hash(), time.Now(), options...)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := inv.Encode(zpay32.MessageSigner{
+		SignCompact: func(hash []byte) ([]byte, error) {
+			return btcec.SignCompact(btcec.S256(), mi.key, hash, true)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := &InvoiceStatus{
+		PaymentRequest: Invoice(encoded),
+		PaymentHash:    preimage.Hash(),
+		State:          InvoiceStateOpen,
+	}
+	mi.invoices[status.PaymentHash] = status
+	mi.created++
+
+	result := *status
+	return &result, nil
+}
+
+// LookupInvoice returns the status of a previously created invoice.
+func (mi *MockInvoicer) LookupInvoice(ctx context.Context, hash lntypes.Hash) (*InvoiceStatus, error) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	status, ok := mi.invoices[hash]
+	if !ok {
+		return nil, fmt.Errorf("invoice %v not found", hash)
+	}
+
+	result := *status
+	return &result, nil
+}
+
+// SubscribeInvoice streams updates for a previously created invoice, starting with its current status.
+func (mi *MockInvoicer) SubscribeInvoice(ctx context.Context, hash lntypes.Hash) (<-chan InvoiceStatus, <-chan error, error) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	status, ok := mi.invoices[hash]
+	if !ok {
+		return nil, nil, fmt.Errorf("invoice %v not found", hash)
+	}
+
+	updates := make(chan InvoiceStatus, 2)
+	errs := make(chan error, 1)
+	updates <- *status
+	if status.State.Final() {
+		close(updates)
+		return updates, errs, nil
+	}
+	mi.subscribers[hash] = append(mi.subscribers[hash], updates)
+
+	go func() {
+		<-ctx.Done()
+
+		mi.mu.Lock()
+		defer mi.mu.Unlock()
+		mi.unsubscribe(hash, updates)
+	}()
+
+	return updates, errs, nil
+}
+
+// Settle simulates the payment of the invoice with the given payment hash and notifies subscribers.
+func (mi *MockInvoicer) Settle(hash lntypes.Hash) error {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	status, ok := mi.invoices[hash]
+	if !ok {
+		return fmt.Errorf("invoice %v not found", hash)
+	}
+
+	inv, err := status.PaymentRequest.Decode()
+	if err != nil {
+		return err
+	}
+
+	status.State = InvoiceStateSettled
+	status.Preimage = MockPreimage
+	status.AmountPaid = inv.Amount
+	status.SettledAt = time.Now()
+
+	for _, updates := range mi.subscribers[hash] {
+		updates <- *status
+		close(updates)
+	}
+	delete(mi.subscribers, hash)
+
+	return nil
+}
+
+// Created returns the number of invoices created so far.
+func (mi *MockInvoicer) Created() int {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	return mi.created
+}
+
+// unsubscribe removes updates from the subscribers of hash and closes it. mi.mu must be held.
+func (mi *MockInvoicer) unsubscribe(hash lntypes.Hash, updates chan InvoiceStatus) {
+	subscribers := mi.subscribers[hash]
+	for i, ch := range subscribers {
+		if ch == updates {
+			mi.subscribers[hash] = append(subscribers[:i], subscribers[i+1:]...)
+			close(updates)
+			return
+		}
+	}
 }
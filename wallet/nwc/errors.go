@@ -0,0 +1,76 @@
+package nwc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes a wallet service reports in NIP-47 responses.
+const (
+	codeRateLimited         = "RATE_LIMITED"
+	codeNotImplemented      = "NOT_IMPLEMENTED"
+	codeInsufficientBalance = "INSUFFICIENT_BALANCE"
+	codeQuotaExceeded       = "QUOTA_EXCEEDED"
+	codeRestricted          = "RESTRICTED"
+	codeUnauthorized        = "UNAUTHORIZED"
+	codePaymentFailed       = "PAYMENT_FAILED"
+)
+
+var (
+	// ErrRateLimited is returned when the client sent too many requests.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrNotImplemented is returned when the wallet service does not support paying invoices.
+	ErrNotImplemented = errors.New("not implemented")
+
+	// ErrInsufficientBalance is returned when the wallet cannot cover the payment.
+	ErrInsufficientBalance = errors.New("insufficient balance")
+
+	// ErrQuotaExceeded is returned when the payment exceeds the budget of the connection.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrRestricted is returned when the connection is not allowed to pay invoices.
+	ErrRestricted = errors.New("restricted")
+
+	// ErrUnauthorized is returned when the wallet service does not know the connection.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrPaymentFailed is returned when the wallet service failed to pay the invoice.
+	ErrPaymentFailed = errors.New("payment failed")
+
+	// ErrRejected is returned when the relay refuses the request event.
+	ErrRejected = errors.New("request rejected by relay")
+)
+
+// Error is an error reported by the wallet service. It unwraps to one of the errors of
+// this package when its code is known, so callers can use errors.Is.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("wallet service error %s: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the typed error for the error code, or nil if the code is unknown.
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case codeRateLimited:
+		return ErrRateLimited
+	case codeNotImplemented:
+		return ErrNotImplemented
+	case codeInsufficientBalance:
+		return ErrInsufficientBalance
+	case codeQuotaExceeded:
+		return ErrQuotaExceeded
+	case codeRestricted:
+		return ErrRestricted
+	case codeUnauthorized:
+		return ErrUnauthorized
+	case codePaymentFailed:
+		return ErrPaymentFailed
+	default:
+		return nil
+	}
+}
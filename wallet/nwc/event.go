@@ -0,0 +1,101 @@
+package nwc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// errInvalidEvent is returned when an event does not match its id or signature.
+var errInvalidEvent = errors.New("invalid event")
+
+// event is a Nostr event as defined by NIP-01.
+type event struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// hash returns the event id, the hash of the canonical serialization of the event.
+func (e *event) hash() ([32]byte, error) {
+	tags := e.Tags
+	if tags == nil {
+		tags = [][]string{}
+	}
+
+	// NIP-01 requires the serialization without HTML escaping or trailing whitespace.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode([]interface{}{0, e.PubKey, e.CreatedAt, e.Kind, tags, e.Content}); err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// sign sets the public key, id and signature of the event.
+func (e *event) sign(key *secp256k1.PrivateKey) error {
+	e.PubKey = hex.EncodeToString(xOnlyPubKey(key))
+
+	id, err := e.hash()
+	if err != nil {
+		return err
+	}
+
+	var aux [32]byte
+	if _, err := rand.Read(aux[:]); err != nil {
+		return err
+	}
+	sig, err := schnorrSign(key, id[:], aux)
+	if err != nil {
+		return err
+	}
+
+	e.ID = hex.EncodeToString(id[:])
+	e.Sig = hex.EncodeToString(sig)
+	return nil
+}
+
+// verify checks the id and signature of the event.
+func (e *event) verify() error {
+	id, err := e.hash()
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(id[:]) != e.ID {
+		return fmt.Errorf("%w: id mismatch", errInvalidEvent)
+	}
+
+	pubKey, err := hex.DecodeString(e.PubKey)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidEvent, err)
+	}
+	sig, err := hex.DecodeString(e.Sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidEvent, err)
+	}
+	if !schnorrVerify(pubKey, id[:], sig) {
+		return fmt.Errorf("%w: bad signature", errInvalidEvent)
+	}
+	return nil
+}
+
+// tag returns the first value of the first tag with the given name.
+func (e *event) tag(name string) string {
+	for _, tag := range e.Tags {
+		if len(tag) >= 2 && tag[0] == name {
+			return tag[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package nwc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// errDecrypt is returned when an encrypted message cannot be decrypted.
+var errDecrypt = errors.New("cannot decrypt message")
+
+// nip04Encrypt encrypts plaintext for pubKey as defined by NIP-04: AES-256-CBC keyed with
+// the x coordinate of the ECDH shared point, encoded as "<ciphertext>?iv=<iv>" in base64.
+func nip04Encrypt(key *secp256k1.PrivateKey, pubKey *secp256k1.PublicKey, plaintext string) (string, error) {
+	block, err := aes.NewCipher(secp256k1.GenerateSharedSecret(key, pubKey))
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append([]byte(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	return base64.StdEncoding.EncodeToString(ciphertext) + "?iv=" + base64.StdEncoding.EncodeToString(iv), nil
+}
+
+// nip04Decrypt decrypts a NIP-04 message sent by pubKey.
+func nip04Decrypt(key *secp256k1.PrivateKey, pubKey *secp256k1.PublicKey, content string) (string, error) {
+	encoded, encodedIV, found := strings.Cut(content, "?iv=")
+	if !found {
+		return "", fmt.Errorf("%w: missing iv", errDecrypt)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errDecrypt, err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(encodedIV)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errDecrypt, err)
+	}
+	if len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("%w: invalid length", errDecrypt)
+	}
+
+	block, err := aes.NewCipher(secp256k1.GenerateSharedSecret(key, pubKey))
+	if err != nil {
+		return "", err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return "", fmt.Errorf("%w: invalid padding", errDecrypt)
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return "", fmt.Errorf("%w: invalid padding", errDecrypt)
+		}
+	}
+	return string(plaintext[:len(plaintext)-padding]), nil
+}
@@ -0,0 +1,291 @@
+package nwc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/gorilla/websocket"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// Event kinds of NIP-47 requests and responses.
+const (
+	kindRequest  = 23194
+	kindResponse = 23195
+)
+
+const methodPayInvoice = "pay_invoice"
+
+type nwcRequest struct {
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+type nwcResponse struct {
+	ResultType string          `json:"result_type"`
+	Error      *Error          `json:"error"`
+	Result     json.RawMessage `json:"result"`
+}
+
+type payInvoiceResult struct {
+	Preimage string `json:"preimage"`
+	FeesPaid int64  `json:"fees_paid"`
+}
+
+// NwcWallet implements the Wallet interface using Nostr Wallet Connect (NIP-47).
+type NwcWallet struct {
+	// Dialer dials the relays. If nil, websocket.DefaultDialer is used.
+	Dialer *websocket.Dialer
+
+	relays       []string
+	walletPubKey *secp256k1.PublicKey
+	walletHex    string
+	secret       *secp256k1.PrivateKey
+	now          func() time.Time
+}
+
+// NewNwcWallet creates a new instance of NwcWallet from a nostr+walletconnect:// connection URI.
+func NewNwcWallet(uri string) (*NwcWallet, error) {
+	conn, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	walletKey, err := hex.DecodeString(conn.WalletPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+	walletPubKey, err := parseXOnlyPubKey(walletKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+	secret, err := hex.DecodeString(conn.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+
+	return &NwcWallet{
+		relays:       conn.Relays,
+		walletPubKey: walletPubKey,
+		walletHex:    conn.WalletPubKey,
+		secret:       secp256k1.PrivKeyFromBytes(secret),
+		now:          time.Now,
+	}, nil
+}
+
+// PayInvoice sends a pay_invoice request to the wallet service and waits for its response.
+// The relays of the connection are tried in order until one accepts the connection.
+// Errors reported by the wallet service are returned as *Error, which matches the errors
+// of this package with errors.Is.
+func (nw *NwcWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	request, err := nw.newRequest(nwcRequest{
+		Method: methodPayInvoice,
+		Params: map[string]string{"invoice": string(invoice)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := nw.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	createdAt := time.Now()
+	var dialErr error
+	for _, relay := range nw.relays {
+		conn, _, err := dialer.DialContext(ctx, relay, nil)
+		if err != nil {
+			dialErr = fmt.Errorf("error connecting to relay %s: %w", relay, err)
+			continue
+		}
+		defer conn.Close()
+
+		response, err := nw.roundTrip(ctx, conn, request)
+		if err != nil {
+			return nil, err
+		}
+
+		var result payInvoiceResult
+		if err := json.Unmarshal(response.Result, &result); err != nil {
+			return nil, fmt.Errorf("error unmarshaling pay_invoice result: %w", err)
+		}
+		return newPaymentResult(invoice, &result, createdAt), nil
+	}
+
+	return nil, dialErr
+}
+
+// newPaymentResult creates the result of a settled payment. NIP-47 only reports the
+// preimage and the fee, so the hash and amount are taken from the preimage and invoice.
+func newPaymentResult(invoice wallet.Invoice, result *payInvoiceResult, createdAt time.Time) *wallet.PaymentResult {
+	paymentResult := &wallet.PaymentResult{
+		Preimage:  result.Preimage,
+		Success:   true,
+		Fee:       lnwire.MilliSatoshi(result.FeesPaid),
+		CreatedAt: createdAt,
+		SettledAt: time.Now(),
+	}
+	if preimage, err := lntypes.MakePreimageFromStr(result.Preimage); err == nil {
+		paymentResult.PaymentHash = preimage.Hash()
+	}
+	if decoded, err := invoice.Decode(); err == nil {
+		paymentResult.Amount = decoded.Amount
+	}
+	return paymentResult
+}
+
+// newRequest creates the signed request event carrying the encrypted request.
+func (nw *NwcWallet) newRequest(req nwcRequest) (*event, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+	content, err := nip04Encrypt(nw.secret, nw.walletPubKey, string(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting request: %w", err)
+	}
+
+	ev := &event{
+		CreatedAt: nw.now().Unix(),
+		Kind:      kindRequest,
+		Tags:      [][]string{{"p", nw.walletHex}},
+		Content:   content,
+	}
+	if err := ev.sign(nw.secret); err != nil {
+		return nil, fmt.Errorf("error signing request: %w", err)
+	}
+	return ev, nil
+}
+
+// roundTrip subscribes to the response of request, publishes request and waits for the response.
+func (nw *NwcWallet) roundTrip(ctx context.Context, conn *websocket.Conn, request *event) (*nwcResponse, error) {
+	// Unblock reads and writes when the context is done.
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	subID, err := newSubscriptionID()
+	if err != nil {
+		return nil, err
+	}
+
+	// Subscribe before publishing so the response cannot be missed.
+	filter := map[string]interface{}{
+		"kinds":   []int{kindResponse},
+		"authors": []string{nw.walletHex},
+		"#e":      []string{request.ID},
+	}
+	if err := conn.WriteJSON([]interface{}{"REQ", subID, filter}); err != nil {
+		return nil, nw.ctxErr(ctx, fmt.Errorf("error subscribing: %w", err))
+	}
+	defer conn.WriteJSON([]interface{}{"CLOSE", subID}) //nolint:errcheck
+
+	if err := conn.WriteJSON([]interface{}{"EVENT", request}); err != nil {
+		return nil, nw.ctxErr(ctx, fmt.Errorf("error publishing request: %w", err))
+	}
+
+	for {
+		var msg []json.RawMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return nil, nw.ctxErr(ctx, fmt.Errorf("error reading from relay: %w", err))
+		}
+		if len(msg) < 2 {
+			continue
+		}
+
+		var label string
+		if err := json.Unmarshal(msg[0], &label); err != nil {
+			continue
+		}
+
+		switch label {
+		case "EVENT":
+			if len(msg) < 3 {
+				continue
+			}
+			var ev event
+			if err := json.Unmarshal(msg[2], &ev); err != nil {
+				continue
+			}
+			response, err := nw.parseResponse(&ev, request)
+			if err != nil {
+				continue
+			}
+			if response.Error != nil {
+				return nil, response.Error
+			}
+			return response, nil
+
+		case "OK":
+			var id, message string
+			var accepted bool
+			if len(msg) < 3 || json.Unmarshal(msg[1], &id) != nil || json.Unmarshal(msg[2], &accepted) != nil {
+				continue
+			}
+			if len(msg) > 3 {
+				json.Unmarshal(msg[3], &message) //nolint:errcheck
+			}
+			if id == request.ID && !accepted {
+				return nil, fmt.Errorf("%w: %s", ErrRejected, message)
+			}
+
+		case "CLOSED":
+			var id, message string
+			json.Unmarshal(msg[1], &id) //nolint:errcheck
+			if len(msg) > 2 {
+				json.Unmarshal(msg[2], &message) //nolint:errcheck
+			}
+			if id == subID {
+				return nil, fmt.Errorf("subscription closed by relay: %s", message)
+			}
+		}
+	}
+}
+
+// parseResponse verifies and decrypts a response event to request.
+// Events that are not a valid response to request are rejected.
+func (nw *NwcWallet) parseResponse(ev *event, request *event) (*nwcResponse, error) {
+	if ev.Kind != kindResponse || ev.PubKey != nw.walletHex || ev.tag("e") != request.ID {
+		return nil, errInvalidEvent
+	}
+	if err := ev.verify(); err != nil {
+		return nil, err
+	}
+
+	payload, err := nip04Decrypt(nw.secret, nw.walletPubKey, ev.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	var response nwcResponse
+	if err := json.Unmarshal([]byte(payload), &response); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return &response, nil
+}
+
+// ctxErr prefers the context error over the I/O error it caused.
+func (nw *NwcWallet) ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// newSubscriptionID returns a random subscription id.
+func newSubscriptionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
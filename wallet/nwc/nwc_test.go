@@ -0,0 +1,396 @@
+package nwc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/gorilla/websocket"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+const testPreimage = "0102030405060708091011121314151617181920212223242526272829303132"
+
+// filter is the subset of NIP-01 subscription filters used by NIP-47.
+type filter struct {
+	Kinds   []int    `json:"kinds"`
+	Authors []string `json:"authors"`
+	E       []string `json:"#e"`
+	P       []string `json:"#p"`
+}
+
+func (f *filter) matches(ev *event) bool {
+	contains := func(values []string, v string) bool {
+		for _, value := range values {
+			if value == v {
+				return true
+			}
+		}
+		return len(values) == 0
+	}
+
+	kindMatches := len(f.Kinds) == 0
+	for _, kind := range f.Kinds {
+		kindMatches = kindMatches || kind == ev.Kind
+	}
+	return kindMatches && contains(f.Authors, ev.PubKey) &&
+		contains(f.E, ev.tag("e")) && contains(f.P, ev.tag("p"))
+}
+
+// relayConn is a connection to the fake relay with its subscriptions.
+type relayConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+	subs map[string]*filter
+}
+
+func (c *relayConn) send(msg ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.WriteJSON(msg) //nolint:errcheck
+}
+
+// fakeRelay is an in-process Nostr relay that forwards events to matching subscriptions.
+type fakeRelay struct {
+	server *httptest.Server
+	reject bool
+
+	mu    sync.Mutex
+	conns map[*relayConn]bool
+}
+
+func newFakeRelay(t *testing.T) *fakeRelay {
+	t.Helper()
+
+	r := &fakeRelay{conns: make(map[*relayConn]bool)}
+	r.server = httptest.NewServer(http.HandlerFunc(r.handler))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *fakeRelay) url() string {
+	return "ws" + strings.TrimPrefix(r.server.URL, "http")
+}
+
+func (r *fakeRelay) handler(w http.ResponseWriter, req *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	c := &relayConn{conn: conn, subs: make(map[string]*filter)}
+	r.mu.Lock()
+	r.conns[c] = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, c)
+		r.mu.Unlock()
+	}()
+
+	for {
+		var msg []json.RawMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		var label, subID string
+		json.Unmarshal(msg[0], &label) //nolint:errcheck
+
+		switch label {
+		case "REQ":
+			var f filter
+			json.Unmarshal(msg[1], &subID) //nolint:errcheck
+			json.Unmarshal(msg[2], &f)     //nolint:errcheck
+			r.mu.Lock()
+			c.subs[subID] = &f
+			r.mu.Unlock()
+			c.send("EOSE", subID)
+
+		case "CLOSE":
+			json.Unmarshal(msg[1], &subID) //nolint:errcheck
+			r.mu.Lock()
+			delete(c.subs, subID)
+			r.mu.Unlock()
+
+		case "EVENT":
+			var ev event
+			json.Unmarshal(msg[1], &ev) //nolint:errcheck
+			if r.reject {
+				c.send("OK", ev.ID, false, "blocked: test relay")
+				continue
+			}
+			if err := ev.verify(); err != nil {
+				c.send("OK", ev.ID, false, "invalid: "+err.Error())
+				continue
+			}
+			c.send("OK", ev.ID, true, "")
+			r.broadcast(&ev)
+		}
+	}
+}
+
+func (r *fakeRelay) broadcast(ev *event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for c := range r.conns {
+		for subID, f := range c.subs {
+			if f.matches(ev) {
+				go c.send("EVENT", subID, ev)
+			}
+		}
+	}
+}
+
+// payHandler answers a pay_invoice request of the fake wallet service.
+type payHandler func(invoice string) (*payInvoiceResult, *Error)
+
+// startWalletService runs a NIP-47 wallet service on the relay until the test ends.
+func startWalletService(t *testing.T, relay *fakeRelay, key *secp256k1.PrivateKey, pay payHandler) {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(relay.url(), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	pubKey := hex.EncodeToString(xOnlyPubKey(key))
+	require.NoError(t, conn.WriteJSON([]interface{}{"REQ", "requests", filter{Kinds: []int{kindRequest}, P: []string{pubKey}}}))
+
+	// Wait for the subscription to be active before the client publishes.
+	var eose []string
+	require.NoError(t, conn.ReadJSON(&eose))
+	require.Equal(t, "EOSE", eose[0])
+
+	go func() {
+		for {
+			var msg []json.RawMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			var label string
+			json.Unmarshal(msg[0], &label) //nolint:errcheck
+			if label != "EVENT" {
+				continue
+			}
+
+			var request event
+			json.Unmarshal(msg[2], &request) //nolint:errcheck
+			response, err := serveRequest(key, &request, pay)
+			if err != nil {
+				t.Errorf("Wallet service failed to serve request: %v", err)
+				return
+			}
+			conn.WriteJSON([]interface{}{"EVENT", response}) //nolint:errcheck
+		}
+	}()
+}
+
+// serveRequest decrypts a request event and returns the signed response event.
+func serveRequest(key *secp256k1.PrivateKey, request *event, pay payHandler) (*event, error) {
+	clientKey, err := hex.DecodeString(request.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	clientPubKey, err := parseXOnlyPubKey(clientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := nip04Decrypt(key, clientPubKey, request.Content)
+	if err != nil {
+		return nil, err
+	}
+	var req struct {
+		Method string            `json:"method"`
+		Params map[string]string `json:"params"`
+	}
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		return nil, err
+	}
+
+	response := map[string]interface{}{"result_type": req.Method}
+	if req.Method != methodPayInvoice {
+		response["error"] = &Error{Code: codeNotImplemented, Message: req.Method}
+	} else if result, payErr := pay(req.Params["invoice"]); payErr != nil {
+		response["error"] = payErr
+	} else {
+		response["result"] = result
+	}
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	content, err := nip04Encrypt(key, clientPubKey, string(encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &event{
+		CreatedAt: time.Now().Unix(),
+		Kind:      kindResponse,
+		Tags:      [][]string{{"p", request.PubKey}, {"e", request.ID}},
+		Content:   content,
+	}
+	return ev, ev.sign(key)
+}
+
+// newTestWallet creates an NwcWallet for a wallet service with key reachable via relays.
+func newTestWallet(t *testing.T, key *secp256k1.PrivateKey, relays ...string) *NwcWallet {
+	t.Helper()
+
+	secret, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	uri := fmt.Sprintf("%s://%x?secret=%x", URIScheme, xOnlyPubKey(key), secret.Serialize())
+	for _, relay := range relays {
+		uri += "&relay=" + relay
+	}
+
+	w, err := NewNwcWallet(uri)
+	require.NoError(t, err)
+	return w
+}
+
+func newTestKey(t *testing.T) *secp256k1.PrivateKey {
+	t.Helper()
+
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	return key
+}
+
+func TestPayInvoice(t *testing.T) {
+	relay := newFakeRelay(t)
+	walletKey := newTestKey(t)
+
+	var paid []string
+	var mu sync.Mutex
+	startWalletService(t, relay, walletKey, func(invoice string) (*payInvoiceResult, *Error) {
+		mu.Lock()
+		defer mu.Unlock()
+		paid = append(paid, invoice)
+		return &payInvoiceResult{Preimage: testPreimage, FeesPaid: 1000}, nil
+	})
+
+	w := newTestWallet(t, walletKey, relay.url())
+	result, err := w.PayInvoice(context.Background(), "lnbc1invoice")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, testPreimage, result.Preimage)
+	require.Equal(t, lnwire.MilliSatoshi(1000), result.Fee)
+
+	preimage, err := lntypes.MakePreimageFromStr(testPreimage)
+	require.NoError(t, err)
+	require.Equal(t, preimage.Hash(), result.PaymentHash)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"lnbc1invoice"}, paid)
+}
+
+func TestPayInvoiceRelayFallback(t *testing.T) {
+	relay := newFakeRelay(t)
+	walletKey := newTestKey(t)
+	startWalletService(t, relay, walletKey, func(string) (*payInvoiceResult, *Error) {
+		return &payInvoiceResult{Preimage: testPreimage}, nil
+	})
+
+	// The first relay is unreachable, so the request goes through the second.
+	w := newTestWallet(t, walletKey, "ws://127.0.0.1:1", relay.url())
+	result, err := w.PayInvoice(context.Background(), "lnbc1invoice")
+	require.NoError(t, err)
+	require.Equal(t, testPreimage, result.Preimage)
+}
+
+func TestPayInvoiceErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		wantError error
+	}{
+		{"Insufficient balance", codeInsufficientBalance, ErrInsufficientBalance},
+		{"Quota exceeded", codeQuotaExceeded, ErrQuotaExceeded},
+		{"Payment failed", codePaymentFailed, ErrPaymentFailed},
+		{"Unauthorized", codeUnauthorized, ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relay := newFakeRelay(t)
+			walletKey := newTestKey(t)
+			startWalletService(t, relay, walletKey, func(string) (*payInvoiceResult, *Error) {
+				return nil, &Error{Code: tt.code, Message: tt.name}
+			})
+
+			_, err := newTestWallet(t, walletKey, relay.url()).PayInvoice(context.Background(), "lnbc1invoice")
+			require.ErrorIs(t, err, tt.wantError)
+
+			var nwcErr *Error
+			require.ErrorAs(t, err, &nwcErr)
+			require.Equal(t, tt.code, nwcErr.Code)
+		})
+	}
+}
+
+func TestPayInvoiceRejected(t *testing.T) {
+	relay := newFakeRelay(t)
+	relay.reject = true
+
+	_, err := newTestWallet(t, newTestKey(t), relay.url()).PayInvoice(context.Background(), "lnbc1invoice")
+	require.ErrorIs(t, err, ErrRejected)
+}
+
+func TestPayInvoiceTimeout(t *testing.T) {
+	// Without a wallet service nobody answers the request.
+	relay := newFakeRelay(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := newTestWallet(t, newTestKey(t), relay.url()).PayInvoice(ctx, "lnbc1invoice")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNip04RoundTrip(t *testing.T) {
+	alice, bob := newTestKey(t), newTestKey(t)
+
+	content, err := nip04Encrypt(alice, bob.PubKey(), "hello bob")
+	require.NoError(t, err)
+	plaintext, err := nip04Decrypt(bob, alice.PubKey(), content)
+	require.NoError(t, err)
+	require.Equal(t, "hello bob", plaintext)
+
+	_, err = nip04Decrypt(bob, newTestKey(t).PubKey(), content)
+	require.Error(t, err)
+}
+
+func TestEventSignature(t *testing.T) {
+	key := newTestKey(t)
+	ev := &event{CreatedAt: 1700000000, Kind: kindRequest, Content: "hello"}
+	require.NoError(t, ev.sign(key))
+	require.NoError(t, ev.verify())
+
+	tampered := *ev
+	tampered.Content = "goodbye"
+	require.ErrorIs(t, tampered.verify(), errInvalidEvent)
+
+	other := &event{CreatedAt: 1700000000, Kind: kindRequest, Content: "goodbye"}
+	require.NoError(t, other.sign(key))
+	tampered = *ev
+	tampered.Sig = other.Sig
+	require.ErrorIs(t, tampered.verify(), errInvalidEvent)
+}
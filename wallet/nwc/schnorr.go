@@ -0,0 +1,129 @@
+package nwc
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Nostr signs events with BIP-340 Schnorr signatures over x-only public keys.
+
+// taggedHash computes the tagged hash of BIP-340.
+func taggedHash(tag string, msgs ...[]byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// xOnlyPubKey returns the 32-byte x-only public key of key.
+func xOnlyPubKey(key *secp256k1.PrivateKey) []byte {
+	return key.PubKey().SerializeCompressed()[1:]
+}
+
+// parseXOnlyPubKey parses a 32-byte x-only public key, which has an even y coordinate.
+func parseXOnlyPubKey(pubKey []byte) (*secp256k1.PublicKey, error) {
+	if len(pubKey) != 32 {
+		return nil, errors.New("x-only public key must be 32 bytes")
+	}
+	return secp256k1.ParsePubKey(append([]byte{secp256k1.PubKeyFormatCompressedEven}, pubKey...))
+}
+
+// schnorrSign signs the 32-byte hash with key using the auxiliary randomness aux.
+func schnorrSign(key *secp256k1.PrivateKey, hash []byte, aux [32]byte) ([]byte, error) {
+	d := key.Key
+	if d.IsZero() {
+		return nil, errors.New("invalid private key")
+	}
+
+	var p secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&d, &p)
+	p.ToAffine()
+	if p.Y.IsOdd() {
+		d.Negate()
+	}
+	pubX := p.X.Bytes()
+
+	auxHash := taggedHash("BIP0340/aux", aux[:])
+	dBytes := d.Bytes()
+	var t [32]byte
+	for i := range t {
+		t[i] = dBytes[i] ^ auxHash[i]
+	}
+
+	nonce := taggedHash("BIP0340/nonce", t[:], pubX[:], hash)
+	var k secp256k1.ModNScalar
+	k.SetBytes(&nonce)
+	if k.IsZero() {
+		return nil, errors.New("invalid nonce")
+	}
+
+	var r secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&k, &r)
+	r.ToAffine()
+	if r.Y.IsOdd() {
+		k.Negate()
+	}
+	rX := r.X.Bytes()
+
+	challenge := taggedHash("BIP0340/challenge", rX[:], pubX[:], hash)
+	var e secp256k1.ModNScalar
+	e.SetBytes(&challenge)
+
+	var s secp256k1.ModNScalar
+	s.Mul2(&e, &d).Add(&k)
+	sBytes := s.Bytes()
+
+	sig := make([]byte, 64)
+	copy(sig[:32], rX[:])
+	copy(sig[32:], sBytes[:])
+	return sig, nil
+}
+
+// schnorrVerify reports whether sig is a valid signature of the 32-byte hash by the
+// x-only public key pubKey.
+func schnorrVerify(pubKey, hash, sig []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	key, err := parseXOnlyPubKey(pubKey)
+	if err != nil {
+		return false
+	}
+
+	var r secp256k1.FieldVal
+	if overflow := r.SetByteSlice(sig[:32]); overflow {
+		return false
+	}
+	var s secp256k1.ModNScalar
+	if overflow := s.SetByteSlice(sig[32:]); overflow {
+		return false
+	}
+
+	challenge := taggedHash("BIP0340/challenge", sig[:32], pubKey, hash)
+	var e secp256k1.ModNScalar
+	e.SetBytes(&challenge)
+	e.Negate()
+
+	// R = s*G - e*P
+	var p, sG, eP, point secp256k1.JacobianPoint
+	key.AsJacobian(&p)
+	secp256k1.ScalarBaseMultNonConst(&s, &sG)
+	secp256k1.ScalarMultNonConst(&e, &p, &eP)
+	secp256k1.AddNonConst(&sG, &eP, &point)
+
+	if point.Z.IsZero() {
+		return false
+	}
+	point.ToAffine()
+	return !point.Y.IsOdd() && point.X.Equals(&r)
+}
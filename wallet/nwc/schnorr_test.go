@@ -0,0 +1,61 @@
+package nwc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// TestSchnorrVectors checks signing and verification against the BIP-340 test vectors.
+func TestSchnorrVectors(t *testing.T) {
+	tests := []struct {
+		secKey string
+		pubKey string
+		aux    string
+		msg    string
+		sig    string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000003",
+			"f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0",
+		},
+		{
+			"b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef",
+			"dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89",
+			"6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de33418906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a",
+		},
+	}
+
+	for _, tt := range tests {
+		key := secp256k1.PrivKeyFromBytes(mustDecodeHex(t, tt.secKey))
+		pubKey := mustDecodeHex(t, tt.pubKey)
+		msg := mustDecodeHex(t, tt.msg)
+		var aux [32]byte
+		copy(aux[:], mustDecodeHex(t, tt.aux))
+
+		require.Equal(t, pubKey, xOnlyPubKey(key))
+
+		sig, err := schnorrSign(key, msg, aux)
+		require.NoError(t, err)
+		require.Equal(t, tt.sig, hex.EncodeToString(sig))
+		require.True(t, schnorrVerify(pubKey, msg, sig))
+
+		sig[63] ^= 1
+		require.False(t, schnorrVerify(pubKey, msg, sig))
+	}
+}
@@ -0,0 +1,77 @@
+package nwc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URIScheme is the scheme of Nostr Wallet Connect connection URIs.
+const URIScheme = "nostr+walletconnect"
+
+// ErrInvalidURI is returned when a connection URI cannot be parsed.
+var ErrInvalidURI = errors.New("invalid nostr wallet connect URI")
+
+// ConnectionURI is a parsed Nostr Wallet Connect connection URI of the form
+// nostr+walletconnect://<wallet pubkey>?relay=<relay url>&secret=<client secret key>.
+type ConnectionURI struct {
+	// WalletPubKey is the hex x-only public key of the wallet service.
+	WalletPubKey string
+	// Relays are the relays the wallet service listens on.
+	Relays []string
+	// Secret is the hex secret key the client signs and encrypts requests with.
+	Secret string
+	// LUD16 is the optional lightning address of the wallet.
+	LUD16 string
+}
+
+// ParseURI parses a Nostr Wallet Connect connection URI.
+func ParseURI(s string) (*ConnectionURI, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+	if u.Scheme != URIScheme {
+		return nil, fmt.Errorf("%w: unexpected scheme %q", ErrInvalidURI, u.Scheme)
+	}
+
+	// Some wallets omit the slashes, which leaves the public key in the opaque part.
+	pubKey := u.Host
+	if pubKey == "" {
+		pubKey = u.Opaque
+	}
+	if !isHexKey(pubKey) {
+		return nil, fmt.Errorf("%w: invalid wallet public key", ErrInvalidURI)
+	}
+
+	query := u.Query()
+	uri := &ConnectionURI{
+		WalletPubKey: strings.ToLower(pubKey),
+		Secret:       strings.ToLower(query.Get("secret")),
+		LUD16:        query.Get("lud16"),
+	}
+	if !isHexKey(uri.Secret) {
+		return nil, fmt.Errorf("%w: invalid secret", ErrInvalidURI)
+	}
+
+	for _, relay := range query["relay"] {
+		relayURL, err := url.Parse(relay)
+		if err != nil || (relayURL.Scheme != "wss" && relayURL.Scheme != "ws") || relayURL.Host == "" {
+			return nil, fmt.Errorf("%w: invalid relay %q", ErrInvalidURI, relay)
+		}
+		uri.Relays = append(uri.Relays, relay)
+	}
+	if len(uri.Relays) == 0 {
+		return nil, fmt.Errorf("%w: missing relay", ErrInvalidURI)
+	}
+
+	return uri, nil
+}
+
+// isHexKey reports whether s is a hex encoded 32-byte key.
+func isHexKey(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == 32
+}
@@ -0,0 +1,50 @@
+package nwc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWalletPubKey = "b889ff5b1513b641e2a139f661a661364979c5beee91842f8f0ef42ab558e9d4"
+	testSecret       = "71a8c14c1407c113601079c4302dab36460f0ccd0ad506f1f2dc73b5100e4f3c"
+)
+
+func TestParseURI(t *testing.T) {
+	uri, err := ParseURI("nostr+walletconnect://" + testWalletPubKey +
+		"?relay=wss%3A%2F%2Frelay.damus.io&relay=wss://nos.lol&secret=" + testSecret + "&lud16=alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, &ConnectionURI{
+		WalletPubKey: testWalletPubKey,
+		Relays:       []string{"wss://relay.damus.io", "wss://nos.lol"},
+		Secret:       testSecret,
+		LUD16:        "alice@example.com",
+	}, uri)
+
+	// The slashes after the scheme are optional.
+	uri, err = ParseURI("nostr+walletconnect:" + testWalletPubKey + "?relay=wss://nos.lol&secret=" + testSecret)
+	require.NoError(t, err)
+	require.Equal(t, testWalletPubKey, uri.WalletPubKey)
+}
+
+func TestParseURIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+	}{
+		{"Wrong scheme", "nostr://" + testWalletPubKey + "?relay=wss://nos.lol&secret=" + testSecret},
+		{"Short public key", "nostr+walletconnect://abcd?relay=wss://nos.lol&secret=" + testSecret},
+		{"Missing secret", "nostr+walletconnect://" + testWalletPubKey + "?relay=wss://nos.lol"},
+		{"Invalid secret", "nostr+walletconnect://" + testWalletPubKey + "?relay=wss://nos.lol&secret=xyz"},
+		{"Missing relay", "nostr+walletconnect://" + testWalletPubKey + "?secret=" + testSecret},
+		{"Non-websocket relay", "nostr+walletconnect://" + testWalletPubKey + "?relay=https://nos.lol&secret=" + testSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseURI(tt.uri)
+			require.ErrorIs(t, err, ErrInvalidURI)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package phoenixd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+// ErrPaymentFailed is returned when phoenixd reports the payment as failed.
+var ErrPaymentFailed = errors.New("payment failed")
+
+type phoenixdPaymentResponse struct {
+	RecipientAmountSat int64  `json:"recipientAmountSat"`
+	RoutingFeeSat      int64  `json:"routingFeeSat"`
+	PaymentID          string `json:"paymentId"`
+	PaymentHash        string `json:"paymentHash"`
+	PaymentPreimage    string `json:"paymentPreimage"`
+	// Reason is set instead of the preimage when the payment failed.
+	Reason string `json:"reason"`
+}
+
+// PhoenixdWallet implements the Wallet interface using the phoenixd HTTP API.
+type PhoenixdWallet struct {
+	// BaseURL is the base URL of the phoenixd API, e.g. http://localhost:9740.
+	BaseURL string
+	// password is the http-password from the phoenixd configuration.
+	password string
+}
+
+// NewPhoenixdWallet creates a new instance of PhoenixdWallet.
+func NewPhoenixdWallet(baseURL, password string) *PhoenixdWallet {
+	return &PhoenixdWallet{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		password: password,
+	}
+}
+
+// PayInvoice pays the given invoice. phoenixd responds once the payment has settled or failed.
+func (pw *PhoenixdWallet) PayInvoice(ctx context.Context, invoice wallet.Invoice) (*wallet.PaymentResult, error) {
+	form := url.Values{}
+	form.Set("invoice", string(invoice))
+
+	createdAt := time.Now()
+	responseBody, err := pw.makeRequest(ctx, "/payinvoice", form)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment phoenixdPaymentResponse
+	if err := json.Unmarshal(responseBody, &payment); err != nil {
+		return nil, fmt.Errorf("error unmarshaling phoenixd response: %w", err)
+	}
+
+	if payment.PaymentPreimage == "" {
+		if payment.Reason != "" {
+			return nil, fmt.Errorf("%w: %s", ErrPaymentFailed, payment.Reason)
+		}
+		return nil, ErrPaymentFailed
+	}
+
+	result := &wallet.PaymentResult{
+		Preimage:  payment.PaymentPreimage,
+		Success:   true,
+		Amount:    lnwire.MilliSatoshi(payment.RecipientAmountSat * 1000),
+		Fee:       lnwire.MilliSatoshi(payment.RoutingFeeSat * 1000),
+		CreatedAt: createdAt,
+		SettledAt: time.Now(),
+		PaymentID: payment.PaymentID,
+	}
+	if hash, err := lntypes.MakeHashFromStr(payment.PaymentHash); err == nil {
+		result.PaymentHash = hash
+	}
+	return result, nil
+}
+
+func (pw *PhoenixdWallet) makeRequest(ctx context.Context, path string, form url.Values) ([]byte, error) {
+	url := fmt.Sprintf("%s%s", pw.BaseURL, path)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	// phoenixd uses basic auth with an empty user name.
+	req.SetBasicAuth("", pw.password)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	// phoenixd reports errors as plain text.
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+
+	return responseBody, nil
+}
@@ -0,0 +1,111 @@
+package phoenixd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+	"github.com/sulusolutions/gol402/wallet"
+)
+
+const (
+	testPassword    = "phoenixdpw"
+	testPaymentHash = "f3a2d4c1f1e2d3c4b5a6978877665544332211ffeeddccbbaa99887766554433"
+	testPreimage    = "0102030405060708091011121314151617181920212223242526272829303132"
+)
+
+// MockPhoenixdServer mimics the payinvoice endpoint of the phoenixd API.
+type MockPhoenixdServer struct {
+	server *httptest.Server
+}
+
+func NewMockPhoenixdServer() *MockPhoenixdServer {
+	mock := &MockPhoenixdServer{}
+	mock.server = httptest.NewServer(http.HandlerFunc(mock.handler))
+	return mock
+}
+
+func (m *MockPhoenixdServer) handler(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || user != "" || password != testPassword {
+		http.Error(w, "Invalid authentication (use basic auth with the http password set in phoenix.conf)", http.StatusUnauthorized)
+		return
+	}
+
+	if r.URL.Path != "/payinvoice" || r.Method != "POST" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.PostFormValue("invoice") {
+	case "lnbc1invoice":
+		w.Write([]byte(`{
+			"recipientAmountSat": 1000,
+			"routingFeeSat": 4,
+			"paymentId": "e5a9b1b2-4c3d-4d5e-8f90-0123456789ab",
+			"paymentHash": "` + testPaymentHash + `",
+			"paymentPreimage": "` + testPreimage + `"
+		}`)) //nolint:errcheck
+	case "lnbc1noroute":
+		w.Write([]byte(`{
+			"paymentId": "e5a9b1b2-4c3d-4d5e-8f90-0123456789ab",
+			"paymentHash": "` + testPaymentHash + `",
+			"reason": "not enough funds"
+		}`)) //nolint:errcheck
+	default:
+		http.Error(w, "Invalid parameter value for invoice", http.StatusBadRequest)
+	}
+}
+
+func (m *MockPhoenixdServer) Close() {
+	m.server.Close()
+}
+
+func (m *MockPhoenixdServer) URL() string {
+	return m.server.URL
+}
+
+func TestPayInvoice(t *testing.T) {
+	s := NewMockPhoenixdServer()
+	defer s.Close()
+
+	w := NewPhoenixdWallet(s.URL(), testPassword)
+	result, err := w.PayInvoice(context.Background(), "lnbc1invoice")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, testPreimage, result.Preimage)
+	require.Equal(t, testPaymentHash, result.PaymentHash.String())
+	require.Equal(t, lnwire.MilliSatoshi(1000000), result.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(4000), result.Fee)
+	require.Equal(t, "e5a9b1b2-4c3d-4d5e-8f90-0123456789ab", result.PaymentID)
+}
+
+func TestPayInvoiceErrors(t *testing.T) {
+	s := NewMockPhoenixdServer()
+	defer s.Close()
+
+	tests := []struct {
+		name      string
+		password  string
+		invoice   string
+		wantError error
+	}{
+		{"Invalid password", "wrong", "lnbc1invoice", nil},
+		{"Payment failed", testPassword, "lnbc1noroute", ErrPaymentFailed},
+		{"Invalid invoice", testPassword, "garbage", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewPhoenixdWallet(s.URL(), tt.password)
+			_, err := w.PayInvoice(context.Background(), wallet.Invoice(tt.invoice))
+			require.Error(t, err)
+			if tt.wantError != nil {
+				require.ErrorIs(t, err, tt.wantError)
+			}
+		})
+	}
+}
@@ -3,21 +3,69 @@ package wallet
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/sulusolutions/gol402/invoice"
 )
 
+// ErrPaymentPending is returned, possibly wrapped, by PayInvoice when a payment was sent but has
+// neither settled nor failed yet. The payment may still settle later, so the invoice should not be
+// replaced by another one until it expires.
+var ErrPaymentPending = errors.New("payment still pending")
+
 // Invoice represents the structure of an invoice for payment.
 type Invoice string
 
-// PaymentResult represents the result of a payment attempt.
+// Decode decodes the BOLT11 payment request held by the invoice.
+func (i Invoice) Decode() (*invoice.Invoice, error) {
+	return invoice.Decode(string(i))
+}
+
+// PaymentResult represents the result of a successful payment.
+// Fields a backend does not report are left at their zero value.
 type PaymentResult struct {
-	// Include fields like Preimage, Success, Error, etc.
+	// Preimage is the hex encoded preimage that proves the payment.
 	Preimage string
-	Success  bool
+
+	// Success is true for every result returned without an error.
+	// It is kept for compatibility; check the error returned by PayInvoice instead.
+	Success bool
+
+	// PaymentHash is the payment hash of the paid invoice.
+	PaymentHash lntypes.Hash
+
+	// Amount is the amount received by the payee, excluding fees.
+	Amount lnwire.MilliSatoshi
+
+	// Fee is the routing fee paid on top of Amount.
+	Fee lnwire.MilliSatoshi
+
+	// CreatedAt is when the payment was initiated.
+	CreatedAt time.Time
+
+	// SettledAt is when the payment settled.
+	SettledAt time.Time
+
+	// Hops is the number of hops of the route the payment took. For payments split
+	// across several routes it is the length of the longest one.
+	Hops int
+
+	// PaymentID is the identifier the backend assigned to the payment.
+	PaymentID string
+}
+
+// Total returns the amount paid including fees.
+func (r *PaymentResult) Total() lnwire.MilliSatoshi {
+	return r.Amount + r.Fee
 }
 
 // Wallet defines the interface for wallet implementations capable of handling L402 payments.
 type Wallet interface {
 	// PayInvoice attempts to pay the given invoice and returns the result.
 	// It should handle necessary logic like decoding the invoice, making the payment through the wallet's API, and returning the preimage if successful.
+	// Payments whose outcome is not known yet are reported with ErrPaymentPending.
 	PayInvoice(ctx context.Context, invoice Invoice) (*PaymentResult, error)
 }